@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/namsral/flag"
+	"github.com/pmdcosta/crawler/internal/diff"
+	"github.com/pmdcosta/crawler/internal/export"
+)
+
+// runDiff compares two saved crawls and returns the exit code
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "output format (text, json)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage of ./crawler diff [flags] old.json new.json:")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	old, err := readCrawl(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	current, err := readCrawl(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	d := diff.Compare(old, current)
+	switch *format {
+	case "text":
+		err = d.WriteText(os.Stdout)
+	case "json":
+		err = d.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %s, expected text or json", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// readCrawl reads a saved crawl file
+func readCrawl(path string) (*export.Crawl, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := export.ReadCrawl(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// mapFlag is a repeatable flag of key=value pairs
+type mapFlag map[string]string
+
+// String returns the string representation of the flag
+func (f mapFlag) String() string {
+	var pairs []string
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set adds a key=value pair to the flag
+func (f mapFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return errors.New("expected key=value")
+	}
+	f[s[:i]] = s[i+1:]
+	return nil
+}
+
+// sliceFlag is a repeatable flag of values
+type sliceFlag []string
+
+// String returns the string representation of the flag
+func (f *sliceFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set adds a value to the flag
+func (f *sliceFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
@@ -1,23 +1,55 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/namsral/flag"
 	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/pmdcosta/crawler/internal/cache"
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/export"
+	"github.com/pmdcosta/crawler/internal/graph"
+	"github.com/pmdcosta/crawler/internal/local"
+	"github.com/pmdcosta/crawler/internal/metrics"
 	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"github.com/pmdcosta/crawler/internal/progress"
+	"github.com/pmdcosta/crawler/internal/replay"
+	"github.com/pmdcosta/crawler/internal/resolver"
 	"github.com/pmdcosta/crawler/internal/scraper"
+	"github.com/pmdcosta/crawler/internal/sink"
+	"github.com/pmdcosta/crawler/internal/storage"
+	"github.com/pmdcosta/crawler/internal/warc"
 	"github.com/pmdcosta/crawler/internal/worker"
 	"github.com/rs/zerolog"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(runDiff(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(os.Args[2:]))
+	}
+
+	// a signal cancels the outstanding requests and stops the crawl, a second one kills the process
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-sigs
+		signal.Stop(sigs)
+		cancel()
+	}()
 	l := zerolog.New(os.Stdout).With().Logger()
 
 	// handle flags
@@ -30,64 +62,450 @@ func main() {
 		filterSubDomain = flag.String("filter-subdomain", "", "only crawl subdomain")
 		filterHost      = flag.String("filter-host", "", "only crawl host")
 		parallel        = flag.Int("parallelism", 10, "number of concurrent requests")
-		output          = flag.String("output", "json", "output format (raw, json)")
+		outputs         sliceFlag
+		loginURL        = flag.String("login-url", "", "login page to authenticate before crawling")
+		loginForm       = flag.String("login-form", "", "css selector of the login form")
+		loginFields     = make(mapFlag)
+		proxy           = flag.String("proxy", "", "proxy url (http, https, socks5)")
+		proxyRules      = make(mapFlag)
+		caCert          = flag.String("ca-cert", "", "pem file with additional certificate authorities")
+		clientCert      = flag.String("client-cert", "", "pem file with the client certificate for mutual tls")
+		clientKey       = flag.String("client-key", "", "pem file with the client certificate key for mutual tls, defaults to the certificate file")
+		insecure        = flag.Bool("insecure", false, "skip the verification of server certificates")
+		tlsMinVersion   = flag.String("tls-min-version", "", "minimum tls version (1.0, 1.1, 1.2, 1.3)")
+		maxIdle         = flag.Int("max-idle-conns", 0, "idle connections kept open across all hosts, 0 is unlimited")
+		maxIdlePerHost  = flag.Int("max-idle-conns-per-host", 0, "idle connections kept open per host, defaults to the parallelism")
+		idleTimeout     = flag.Duration("idle-conn-timeout", 0, "how long an idle connection is kept open")
+		noConnReuse     = flag.Bool("no-conn-reuse", false, "open a new connection for each request")
+		keepAlive       = flag.Duration("keep-alive", 0, "interval between tcp keep-alive probes, negative disables the probes")
+		dialTimeout     = flag.Duration("dial-timeout", 0, "timeout to establish a connection")
+		tlsTimeout      = flag.Duration("tls-timeout", 0, "timeout to perform the tls handshake")
+		headerTimeout   = flag.Duration("response-header-timeout", 0, "timeout to receive the response headers")
+		httpVersion     = flag.String("http-version", "auto", "http version (auto, 1.1, 2)")
+		dnsServer       = flag.String("dns-server", "", "dns server address used instead of the system resolver")
+		dnsTTL          = flag.Duration("dns-ttl", 5*time.Minute, "how long resolved hosts are cached")
+		dnsNegativeTTL  = flag.Duration("dns-negative-ttl", 30*time.Second, "how long failed lookups are cached")
+		resolve         sliceFlag
+		cacheDir        = flag.String("cache-dir", "", "directory to cache pages and revalidate them on recrawls")
+		offline         = flag.Bool("offline", false, "serve pages only from the cache")
+		warcDir         = flag.String("warc-dir", "", "directory to archive the responses as warc files")
+		warcPrefix      = flag.String("warc-prefix", "crawl", "prefix of the warc file names")
+		warcMaxSize     = flag.Int64("warc-max-size", 1<<30, "size in bytes after which a new warc file is started")
+		replayPaths     sliceFlag
+		replayStrict    = flag.Bool("replay-strict", false, "fail the pages that were not recorded instead of returning a 404")
+		localDir        = flag.String("local-dir", "", "serve the host from a local directory instead of the network")
+		maxPages        = flag.Int("max-pages", 0, "max number of pages fetched")
+		maxPagesPerHost = flag.Int("max-pages-per-host", 0, "max number of pages fetched from each host")
+		maxURLsPerPath  = flag.Int("max-urls-per-path", 0, "max number of pages fetched under each path")
+		pathDepth       = flag.Int("path-depth", 0, "directories of the path shared by the pages of max-urls-per-path, 0 uses the whole parent directory")
+		maxBytes        = flag.Int64("max-bytes", 0, "max number of bytes downloaded")
+		maxDuration     = flag.Duration("max-duration", 0, "max duration of the crawl")
+		maxURLLength    = flag.Int("max-url-length", 2048, "max length of the urls crawled, 0 disables the trap detection")
+		maxPathRepeats  = flag.Int("max-path-repeats", 3, "max times the same segment can appear in a path, 0 disables the trap detection")
+		maxQueryVars    = flag.Int("max-query-variants", 0, "max distinct queries crawled for the same path, 0 disables the trap detection")
+		maxSimilarLinks = flag.Int("max-similar-links", 0, "max links in a page numbered in sequence, 0 disables the trap detection")
+		strategy        = flag.String("strategy", orchestrator.StrategyBFS, "crawl ordering strategy (bfs, dfs, best-first, opic, round-robin)")
+		priorities      sliceFlag
+		outputDir       = flag.String("output-dir", ".", "directory the file outputs are written to")
+		slowThreshold   = flag.Duration("slow-threshold", 2*time.Second, "pages taking longer to be fetched are reported as slow, 0 disables the check")
+		failOn          = flag.String("fail-on", "", "exit with an error if a check finds a problem at least this severe (error, warning, note)")
+		collapse        = flag.String("collapse", "", "collapse the graph outputs by host or path prefix (host, path, path:N)")
+		report          = flag.String("report", "", "report printed on stderr after the output (graph)")
+		database        = flag.String("db", "", "sqlite database the crawl runs are stored in")
+		metricsAddr     = flag.String("metrics-addr", "", "address the prometheus metrics are served on, as host:port")
+		progressFormat  = flag.String("progress", progress.Auto, "progress reported on stderr (auto, off, text, json), auto shows the text progress on a terminal")
 	)
+	flag.Var(&outputs, "output", "output format and optional destination as format[=destination], - is the standard output (raw, json, ndjson, importance, graphml, gexf, dot, csv, tsv, html, junit, sarif, crawl) (repeatable)")
+	flag.Var(loginFields, "login-field", "login form field as name=value (repeatable)")
+	flag.Var(&replayPaths, "replay", "serve the pages from a recorded warc file or fixture directory instead of the network (repeatable)")
+	flag.Var(&priorities, "priority", "url regexp weighting the best-first strategy as pattern=weight (repeatable)")
+	flag.Var(&resolve, "resolve", "resolve a host to static addresses as host:port:addr[,addr] (repeatable)")
+	flag.Var(proxyRules, "proxy-rule", "proxy url for a host and its subdomains as host=url, or host=direct (repeatable)")
 	flag.Parse()
 
 	// handle flags
 	if !*debug {
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	}
+	reportFormat, err := progress.ParseFormat(*progressFormat, os.Stderr)
+	if err != nil {
+		l.Fatal().Err(err).Str("progress", *progressFormat).Msg("invalid progress")
+	}
+	if reportFormat == progress.Text && !*debug {
+		// the progress replaces the log line of each page
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	}
 	if *host == "" {
 		l.Fatal().Msg("host to crawl is required")
 	}
+	seed, err := url.Parse(*host)
+	if err != nil {
+		l.Fatal().Err(err).Msg("invalid host to crawl")
+	}
 	var options = []orchestrator.Option{
 		orchestrator.SetMaxRetries(*retries),
 	}
 	if *depth != 0 {
 		options = append(options, orchestrator.SetMaxDepth(*depth))
 	}
-	if *sameHost {
-		u, _ := url.Parse(*host)
-		options = append(options, orchestrator.AddSudDomainFilters(u.Host))
+	if *sameHost && seed.Scheme == "file" {
+		// file urls are only crawled inside the directory of the host
+		dir := seed.String()[:strings.LastIndex(seed.String(), "/")+1]
+		options = append(options, orchestrator.AddCustomFilter(func(u string) bool {
+			return strings.HasPrefix(u, dir)
+		}))
+	} else if *sameHost {
+		options = append(options, orchestrator.AddSudDomainFilters(seed.Host))
+	}
+	if *maxPages != 0 {
+		options = append(options, orchestrator.SetMaxPages(*maxPages))
+	}
+	if *maxPagesPerHost != 0 {
+		options = append(options, orchestrator.SetMaxPagesPerHost(*maxPagesPerHost))
+	}
+	if *maxURLsPerPath != 0 {
+		options = append(options, orchestrator.SetMaxURLsPerPath(*maxURLsPerPath))
 	}
+	if *pathDepth != 0 {
+		options = append(options, orchestrator.SetPathDepth(*pathDepth))
+	}
+	if *maxBytes != 0 {
+		options = append(options, orchestrator.SetMaxBytes(*maxBytes))
+	}
+	if *maxDuration != 0 {
+		options = append(options, orchestrator.SetMaxDuration(*maxDuration))
+	}
+	options = append(options,
+		orchestrator.SetMaxURLLength(*maxURLLength),
+		orchestrator.SetMaxPathRepeats(*maxPathRepeats),
+		orchestrator.SetMaxQueryVariants(*maxQueryVars),
+		orchestrator.SetMaxSimilarLinks(*maxSimilarLinks),
+	)
+	weights := make(map[string]float64)
+	for _, p := range priorities {
+		i := strings.LastIndex(p, "=")
+		if i <= 0 {
+			l.Fatal().Str("priority", p).Msg("invalid priority, expected pattern=weight")
+		}
+		w, err := strconv.ParseFloat(p[i+1:], 64)
+		if err != nil {
+			l.Fatal().Err(err).Str("priority", p).Msg("invalid priority weight")
+		}
+		weights[p[:i]] = w
+	}
+	score, err := orchestrator.PatternScore(weights)
+	if err != nil {
+		l.Fatal().Err(err).Msg("invalid priority pattern")
+	}
+	scheduler, err := orchestrator.NewScheduler(*strategy, score)
+	if err != nil {
+		l.Fatal().Err(err).Str("strategy", *strategy).Msg("invalid strategy")
+	}
+	options = append(options, orchestrator.SetScheduler(scheduler))
 	if *filterSubDomain != "" {
 		options = append(options, orchestrator.AddExactHostFilter(*filterSubDomain))
 	}
 	if *filterHost != "" {
 		options = append(options, orchestrator.AddSudDomainFilters(*filterHost))
 	}
+	var backendOptions []backend.Option
+	if *maxIdlePerHost == 0 {
+		*maxIdlePerHost = *parallel
+	}
+	backendOptions = append(backendOptions, backend.SetMaxIdleConnsPerHost(*maxIdlePerHost))
+	if *maxIdle != 0 {
+		backendOptions = append(backendOptions, backend.SetMaxIdleConns(*maxIdle))
+	}
+	if *idleTimeout != 0 {
+		backendOptions = append(backendOptions, backend.SetIdleConnTimeout(*idleTimeout))
+	}
+	if *noConnReuse {
+		backendOptions = append(backendOptions, backend.DisableConnReuse())
+	}
+	if *keepAlive != 0 {
+		backendOptions = append(backendOptions, backend.SetKeepAlive(*keepAlive))
+	}
+	if *dialTimeout != 0 {
+		backendOptions = append(backendOptions, backend.SetDialTimeout(*dialTimeout))
+	}
+	if *tlsTimeout != 0 {
+		backendOptions = append(backendOptions, backend.SetTLSHandshakeTimeout(*tlsTimeout))
+	}
+	if *headerTimeout != 0 {
+		backendOptions = append(backendOptions, backend.SetResponseHeaderTimeout(*headerTimeout))
+	}
+	resolverOptions := []resolver.Option{
+		resolver.SetTTL(*dnsTTL),
+		resolver.SetNegativeTTL(*dnsNegativeTTL),
+	}
+	if *dnsServer != "" {
+		resolverOptions = append(resolverOptions, resolver.SetNameserver(*dnsServer))
+	}
+	for _, r := range resolve {
+		opt, err := resolver.ParseOverride(r)
+		if err != nil {
+			l.Fatal().Err(err).Str("resolve", r).Msg("invalid resolve override")
+		}
+		resolverOptions = append(resolverOptions, opt)
+	}
+	backendOptions = append(backendOptions, backend.SetResolver(resolver.New(resolverOptions...)))
+	switch *httpVersion {
+	case "auto":
+	case "1.1":
+		backendOptions = append(backendOptions, backend.ForceHTTP1())
+	case "2":
+		backendOptions = append(backendOptions, backend.ForceHTTP2())
+	default:
+		l.Fatal().Str("version", *httpVersion).Msg("invalid http version")
+	}
+	if *proxy != "" {
+		u, err := backend.ParseProxy(*proxy)
+		if err != nil {
+			l.Fatal().Err(err).Msg("invalid proxy")
+		}
+		backendOptions = append(backendOptions, backend.SetProxy(u))
+	}
+	for h, p := range proxyRules {
+		if p == "direct" {
+			backendOptions = append(backendOptions, backend.AddProxyRule(h, nil))
+			continue
+		}
+		u, err := backend.ParseProxy(p)
+		if err != nil {
+			l.Fatal().Err(err).Str("host", h).Msg("invalid proxy rule")
+		}
+		backendOptions = append(backendOptions, backend.AddProxyRule(h, u))
+	}
+	if *caCert != "" {
+		pool, err := backend.LoadCertPool(*caCert)
+		if err != nil {
+			l.Fatal().Err(err).Msg("failed to load ca certificates")
+		}
+		backendOptions = append(backendOptions, backend.SetRootCAs(pool))
+	}
+	if *clientCert != "" {
+		key := *clientKey
+		if key == "" {
+			key = *clientCert
+		}
+		cert, err := tls.LoadX509KeyPair(*clientCert, key)
+		if err != nil {
+			l.Fatal().Err(err).Msg("failed to load client certificate")
+		}
+		backendOptions = append(backendOptions, backend.AddClientCertificate(cert))
+	}
+	if *insecure {
+		backendOptions = append(backendOptions, backend.SetInsecureSkipVerify(true))
+	}
+	if *tlsMinVersion != "" {
+		v, err := backend.ParseTLSVersion(*tlsMinVersion)
+		if err != nil {
+			l.Fatal().Err(err).Msg("invalid tls version")
+		}
+		backendOptions = append(backendOptions, backend.SetMinTLSVersion(v))
+	}
+	if *loginURL != "" {
+		jar, _ := cookiejar.New(nil)
+		backendOptions = append(backendOptions, backend.SetCookieJar(jar))
+		options = append(options, orchestrator.AddCustomFilter(orchestrator.LogoutFilter))
+	}
+	httpBackend := backend.New(&l, backendOptions...)
+
+	// only the requests sent over the network are instrumented, not the pages served from the cache or a recording
+	var m *metrics.Metrics
+	var fetcher interface {
+		worker.Backend
+		cache.Backend
+	} = httpBackend
+	if *metricsAddr != "" {
+		m = metrics.New()
+		fetcher = metrics.NewBackend(httpBackend, m)
+	}
+	var b worker.Backend = fetcher
+	network := !*offline
+	if len(replayPaths) > 0 {
+		r := replay.New(&l, replay.SetStrict(*replayStrict))
+		for _, p := range replayPaths {
+			if err := r.Load(p); err != nil {
+				l.Fatal().Err(err).Str("path", p).Msg("failed to load recorded pages")
+			}
+		}
+		b, network = r, false
+	} else if seed.Scheme == "file" || *localDir != "" {
+		var localOptions []local.Option
+		if *localDir != "" {
+			localOptions = append(localOptions, local.AddVirtualHost(seed.Host, *localDir))
+		}
+		b, network = local.New(&l, localOptions...), false
+	} else if *cacheDir != "" {
+		b = cache.New(&l, *cacheDir, fetcher, cache.SetOffline(*offline))
+	} else if *offline {
+		l.Fatal().Msg("offline mode requires a cache directory")
+	}
+
+	// serve the metrics of the crawl
+	workerOptions := []worker.Option{worker.SetContext(ctx)}
+	if m != nil {
+		options = append(options, orchestrator.SetMetrics(m))
+		workerOptions = append(workerOptions, worker.SetMetrics(m))
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.Handler())
+		go func() {
+			if err := http.ListenAndServe(*metricsAddr, mux); err != nil {
+				l.Error().Err(err).Msg("failed to serve metrics")
+			}
+		}()
+	}
+
+	// authenticate before crawling
+	if *loginURL != "" && network {
+		u, err := url.Parse(*loginURL)
+		if err != nil {
+			l.Fatal().Err(err).Msg("invalid login url")
+		}
+		if err := httpBackend.Login(ctx, u, loginFields, *loginForm); err != nil {
+			l.Fatal().Err(err).Msg("failed to login")
+		}
+	}
+
+	// extract the details of the pages shown by the outputs, the titles are also stored in the database
+	if len(outputs) == 0 {
+		outputs = sliceFlag{sink.JSON}
+	}
+	titles, anchors := *database != "", false
+	for _, o := range outputs {
+		title, anchor := sink.Details(strings.SplitN(o, "=", 2)[0])
+		titles, anchors = titles || title, anchors || anchor
+	}
+	workerOptions = append(workerOptions, worker.AddPostProcessor(scraper.AddDetails(titles, anchors)))
+
+	// archive the responses
+	var archive *warc.Writer
+	if *warcDir != "" {
+		archive = warc.New(&l, *warcDir, warc.SetPrefix(*warcPrefix), warc.SetMaxSize(*warcMaxSize))
+		workerOptions = append(workerOptions, worker.AddPostProcessor(archive.PostProcess))
+	}
+
+	if *failOn != "" {
+		if _, err := export.ParseSeverity(*failOn); err != nil {
+			l.Fatal().Err(err).Str("severity", *failOn).Msg("invalid fail-on severity")
+		}
+	}
+	var collapseBy graph.Collapse
+	if *collapse != "" {
+		if collapseBy, err = graph.ParseCollapse(*collapse); err != nil {
+			l.Fatal().Err(err).Str("collapse", *collapse).Msg("invalid collapse")
+		}
+	}
+
+	// outputs of the crawl, the pages are sent to the sinks as they are crawled
+	threshold := *failOn
+	if threshold == "" {
+		threshold = export.SeverityError
+	}
+	sinks, err := sink.ParseAll(outputs, sink.SetDir(*outputDir), sink.SetCollapse(collapseBy), sink.SetThreshold(threshold))
+	if err != nil {
+		l.Fatal().Err(err).Msg("invalid output")
+	}
+	// the pages saved to the database are not kept in memory, they are read back for the outputs
+	var store *storage.SQLite
+	if *database != "" {
+		if store, err = storage.New(&l, *database); err != nil {
+			l.Fatal().Err(err).Str("db", *database).Msg("failed to open database")
+		}
+		if err := store.StartRun(seed.String()); err != nil {
+			l.Fatal().Err(err).Msg("failed to start crawl run")
+		}
+		options = append(options, orchestrator.SetStorage(store))
+	}
+	options = append(options, orchestrator.AddObserver(sinks.Observer(func(result crawler.TaskResult, err error) {
+		l.Error().Err(err).Str("url", result.URL.String()).Msg("failed to write page")
+	})))
 
 	// initiate the crawler
-	o := orchestrator.New(&l, 10000000, options...)
+	// the tasks are kept in the scheduler so only a few are queued to the workers at a time
+	o := orchestrator.New(&l, *parallel, options...)
 	var workers []*worker.Worker
 	for i := 0; i < *parallel; i++ {
-		w := worker.New(&l, o.TaskQueue, o.DoneQueue, o.ErrorQueue, backend.New(&l), scraper.ScrapePage)
+		w := worker.New(&l, o.TaskQueue, o.DoneQueue, o.ErrorQueue, b, scraper.ScrapePage, workerOptions...)
 		_ = w.Start()
 		workers = append(workers, w)
 	}
 
 	// start crawling
 	_ = o.Start(*host)
+	reporter := progress.New(os.Stderr, reportFormat, o.Stats)
+	if reportFormat != progress.Off {
+		reporter.Start()
+	}
 
 	// wait for signal or end
 	select {
 	case <-o.Done():
-	case <-sigs:
+	case <-ctx.Done():
 		l.Info().Msg("Interrupted crawling")
 	}
 
-	// stop workers
+	// stop workers, the interrupted tasks are returned to the orchestrator before it is stopped
+	cancel()
 	for _, w := range workers {
 		w.Stop()
 	}
 	o.Stop()
+	if pending := o.Stats().Queued; pending > 0 {
+		l.Info().Int("pending", pending).Msg("tasks left in the frontier")
+	}
+	reporter.Stop()
+	if archive != nil {
+		if err := archive.Close(); err != nil {
+			l.Error().Err(err).Msg("failed to close warc archive")
+		}
+	}
+
+	processed, failed := o.Processed, o.Failed
+	if store != nil {
+		// the pages are only read back when an output, the report or the checks need them
+		if sinks.Summarized() || *report != "" || *failOn != "" {
+			if processed, failed, err = store.Load(store.Run()); err != nil {
+				l.Error().Err(err).Msg("failed to read the crawled pages")
+			}
+		}
+		if err := store.FinishRun(); err != nil {
+			l.Error().Err(err).Msg("failed to finish crawl run")
+		}
+		_ = store.Close()
+	}
+
+	// check the crawled pages for problems
+	pages := export.Pages(processed, failed)
+	findings := export.Check(pages, *slowThreshold)
+	for _, t := range o.Traps() {
+		findings = append(findings, export.TrapFinding(t.Pattern, t.Reason, t.URL, t.Throttled))
+	}
 
 	// output
-	if *output == "json" {
-		fmt.Println(o.GetJson())
-	} else if *output == "raw" {
-		fmt.Println(o.GetHits())
+	summary := sink.Summary{
+		Seed:       seed.String(),
+		Processed:  processed,
+		Failed:     failed,
+		Pages:      pages,
+		Importance: o.GetImportance(),
+		Findings:   findings,
+	}
+	if err := sinks.Close(&summary); err != nil {
+		l.Error().Err(err).Msg("failed to write output")
+	}
+	if *report == "graph" {
+		if err := graph.New(processed).Analyze(seed.String()).WriteSummary(os.Stderr); err != nil {
+			l.Error().Err(err).Msg("failed to write graph report")
+		}
+	}
+	for _, t := range o.Traps() {
+		l.Warn().Str("pattern", t.Pattern).Str("reason", t.Reason).Str("url", t.URL).Int("throttled", t.Throttled).Msg("crawler trap")
+	}
+	l.Info().Int("hits", o.Stats().Processed).Str("budget", o.Exhausted()).Int("traps", len(o.Traps())).Int("findings", len(findings)).Msg("Finished crawling")
+	if *failOn != "" && export.Exceeds(findings, *failOn) {
+		l.Error().Str("severity", *failOn).Msg("crawl checks failed")
+		os.Exit(1)
 	}
-	l.Info().Int("hits", len(o.Processed)).Msg("Finished crawling")
 }
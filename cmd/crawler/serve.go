@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/namsral/flag"
+	"github.com/pmdcosta/crawler/internal/server"
+	"github.com/rs/zerolog"
+)
+
+// runServe runs the http api to submit and monitor crawl jobs and returns the exit code
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address the api listens on")
+	maxJobs := fs.Int("max-jobs", 2, "max number of jobs running at the same time")
+	maxParallelism := fs.Int("max-parallelism", 50, "max number of concurrent requests of a job, 0 is unlimited")
+	maxDepth := fs.Int("max-depth", 10, "max depth of a job, 0 is unlimited")
+	retention := fs.Duration("retention", time.Hour, "how long the finished jobs are kept, 0 keeps them until deleted")
+	interval := fs.Duration("progress-interval", time.Second, "interval between the progress events")
+	debug := fs.Bool("debug", false, "increase verbosity")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage of ./crawler serve [flags]:")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if *maxJobs <= 0 || *maxParallelism < 0 || *maxDepth < 0 || *retention < 0 {
+		fs.Usage()
+		return 2
+	}
+	if !*debug {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
+	l := zerolog.New(os.Stdout).With().Logger()
+
+	s := server.New(&l,
+		server.SetMaxJobs(*maxJobs),
+		server.SetMaxParallelism(*maxParallelism),
+		server.SetMaxDepth(*maxDepth),
+		server.SetRetention(*retention),
+		server.SetInterval(*interval),
+	)
+	httpServer := &http.Server{Addr: *addr, Handler: s}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+	l.Info().Str("addr", *addr).Int("max-jobs", *maxJobs).Msg("serving crawl api")
+
+	// wait for signal or failure
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errCh:
+		l.Error().Err(err).Msg("failed to serve crawl api")
+		return 1
+	case <-sigs:
+		l.Info().Msg("stopping crawl api")
+	}
+
+	// cancel the jobs, then close the open streams
+	s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		l.Error().Err(err).Msg("failed to stop crawl api")
+		return 1
+	}
+	return 0
+}
@@ -6,6 +6,9 @@ require (
 	github.com/PuerkitoBio/goquery v1.5.0
 	github.com/golang/mock v1.3.1
 	github.com/namsral/flag v1.7.4-pre
+	github.com/prometheus/client_golang v1.7.1
 	github.com/rs/zerolog v1.16.0
-	github.com/stretchr/testify v1.3.0
+	github.com/stretchr/testify v1.4.0
+	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf
+	modernc.org/sqlite v1.10.6
 )
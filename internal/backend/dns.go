@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/pmdcosta/crawler/internal/resolver"
+)
+
+// ErrDNS is returned when the host name of a request cannot be resolved
+var ErrDNS = errors.New("dns resolution failed")
+
+// SetResolver sets the resolver used to look up the host names
+func SetResolver(r *resolver.Resolver) Option {
+	return func(b *Http) {
+		b.resolver = r
+		b.transport.DialContext = b.dialContext
+	}
+}
+
+// dialContext connects to the address resolving its host name with the resolver
+func (b *Http) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := b.resolver.LookupHost(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	// try each address until a connection is established
+	var conn net.Conn
+	err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	for _, a := range addrs {
+		conn, err = b.dialer.DialContext(ctx, network, net.JoinHostPort(a, port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// wrapError classifies the errors of a request
+func wrapError(err error) error {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return &dnsError{err: err}
+	}
+	return err
+}
+
+// dnsError is a failed resolution, it matches ErrDNS and wraps the error of the request
+type dnsError struct {
+	err error
+}
+
+// Error returns the error message
+func (e *dnsError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrDNS, e.err)
+}
+
+// Is matches ErrDNS
+func (e *dnsError) Is(target error) bool {
+	return target == ErrDNS
+}
+
+// Unwrap returns the error of the request
+func (e *dnsError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,44 @@
+package backend_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/pmdcosta/crawler/internal/resolver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_resolver(t *testing.T) {
+	// generate a test server so we can capture and inspect the request
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte(req.Host))
+	}))
+	defer testServer.Close()
+	u, _ := url.Parse(testServer.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	// build backend resolving the host to the test server
+	logger := zerolog.Nop()
+	r := resolver.New(resolver.AddOverride("staging.google.com", port, "127.0.0.1"), resolver.SetNameserver("127.0.0.1:1"))
+	client := backend.New(&logger, backend.SetResolver(r))
+
+	// execute http request
+	u, _ = url.Parse("http://staging.google.com:" + port)
+	res, err := client.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "staging.google.com:"+port, string(res.Body))
+
+	// dns failures are reported as dns errors
+	u, _ = url.Parse("http://google.com")
+	_, err = client.Do(context.Background(), u)
+	require.True(t, errors.Is(err, backend.ErrDNS))
+	var dnsErr *net.DNSError
+	require.True(t, errors.As(err, &dnsErr))
+}
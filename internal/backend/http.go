@@ -2,20 +2,44 @@ package backend
 
 import (
 	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/resolver"
 	"github.com/rs/zerolog"
 )
 
+// maxRedirects is the number of redirects followed before a request fails
+const maxRedirects = 10
+
+// ErrRedirectLoop is returned when a page redirects back to a page already requested or through too many redirects
+var ErrRedirectLoop = errors.New("redirect loop")
+
 // Http is the default http backend for the crawler
 type Http struct {
-	logger *zerolog.Logger
-	client *http.Client
+	logger    *zerolog.Logger
+	client    *http.Client
+	transport *http.Transport
+	dialer    *net.Dialer
+	resolver  *resolver.Resolver
+
+	// whether to fail responses not served over http/2
+	http2 bool
+
+	// proxy used for hosts without a proxy rule
+	proxy *url.URL
+	// proxies used for specific hosts and their subdomains
+	proxyRules map[string]*url.URL
 
 	// maximum body size per request
 	maxBodySize int
@@ -30,10 +54,20 @@ type Option func(b *Http)
 // New instantiates a new http client
 func New(logger *zerolog.Logger, opts ...Option) *Http {
 	l := logger.With().Str("pkg", "http").Logger()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialContext = dialer.DialContext
 	b := Http{
-		logger: &l,
+		logger:    &l,
+		transport: transport,
+		dialer:    dialer,
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:       10 * time.Second,
+			Transport:     transport,
+			CheckRedirect: checkRedirect,
 		},
 	}
 	for _, opt := range opts {
@@ -42,6 +76,19 @@ func New(logger *zerolog.Logger, opts ...Option) *Http {
 	return &b
 }
 
+// checkRedirect stops following the redirects when they loop back to a page already requested, such as A -> B -> A
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	for _, r := range via {
+		if r.URL.String() == req.URL.String() {
+			return fmt.Errorf("%w: %s redirects back to %s", ErrRedirectLoop, via[len(via)-1].URL, req.URL)
+		}
+	}
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrRedirectLoop, maxRedirects)
+	}
+	return nil
+}
+
 // SetTimeout changes the client timeout
 func SetTimeout(t time.Duration) Option {
 	return func(b *Http) {
@@ -56,6 +103,13 @@ func SetMaxBodySize(s int) Option {
 	}
 }
 
+// SetCookieJar sets the cookie jar used to store cookies between requests
+func SetCookieJar(jar http.CookieJar) Option {
+	return func(b *Http) {
+		b.client.Jar = jar
+	}
+}
+
 // SetHTTPRequest changes the default http request
 func SetHTTPRequest(request *http.Request) Option {
 	return func(b *Http) {
@@ -63,30 +117,58 @@ func SetHTTPRequest(request *http.Request) Option {
 	}
 }
 
-// Do executes the http request
-func (b *Http) Do(u *url.URL) ([]byte, error) {
+// Do executes the http request, which is aborted when the context is cancelled
+func (b *Http) Do(ctx context.Context, u *url.URL) (*crawler.Response, error) {
+	return b.DoWithHeader(ctx, u, nil)
+}
+
+// DoWithHeader executes the http request adding the headers to the request
+func (b *Http) DoWithHeader(ctx context.Context, u *url.URL, header http.Header) (*crawler.Response, error) {
 	start := time.Now()
 	b.logger.Debug().Str("url", u.String()).Msg("executing http request")
 
+	// trace the phases of the request
+	t := tracer{start: start}
+	ctx = httptrace.WithClientTrace(ctx, t.trace())
+
 	// whether to use a custom request
 	var request *http.Request
 	if b.request != nil {
-		request = b.request
+		request = b.request.WithContext(ctx)
 		request.URL = u
 	} else {
-		request, _ = http.NewRequest(http.MethodGet, u.String(), nil)
+		request, _ = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	}
+	if header != nil {
+		h := make(http.Header)
+		for k, v := range request.Header {
+			h[k] = v
+		}
+		for k, v := range header {
+			h[k] = v
+		}
+		request.Header = h
 	}
 
 	// execute the http request
 	res, err := b.client.Do(request)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
 	defer res.Body.Close()
-	if res.Request != nil {
-		*request = *res.Request
+	if b.http2 && res.ProtoMajor != 2 {
+		return nil, ErrHTTP2Unsupported
 	}
-	b.logger.Debug().Str("url", request.URL.String()).Str("status", res.Status).Int("code", res.StatusCode).Dur("elapsed", time.Since(start)).Msg("completed http request")
+	response := crawler.Response{
+		URL:           res.Request.URL,
+		Redirects:     redirects(res),
+		StatusCode:    res.StatusCode,
+		Header:        res.Header,
+		Method:        res.Request.Method,
+		RequestHeader: res.Request.Header,
+		Time:          start,
+	}
+	b.logger.Debug().Str("url", response.URL.String()).Str("status", res.Status).Int("code", res.StatusCode).Dur("elapsed", time.Since(start)).Msg("completed http request")
 
 	// limit body size
 	var bodyReader io.Reader = res.Body
@@ -103,15 +185,22 @@ func (b *Http) Do(u *url.URL) ([]byte, error) {
 		defer bodyReader.(*gzip.Reader).Close()
 	}
 
-	// check if the request was successful
-	if res.StatusCode >= http.StatusInternalServerError {
-		return nil, nil
-	}
-
-	// read response body
+	// read response body, server errors are read as well so they can be archived
 	body, err := ioutil.ReadAll(bodyReader)
 	if err != nil {
 		return nil, err
 	}
-	return body, nil
+	response.Body = body
+	response.Size = len(body)
+	t.done(&response)
+	return &response, nil
+}
+
+// redirects returns the redirects followed to get a response, in order
+func redirects(res *http.Response) []crawler.Redirect {
+	var result []crawler.Redirect
+	for r := res.Request.Response; r != nil; r = r.Request.Response {
+		result = append([]crawler.Redirect{{URL: r.Request.URL, StatusCode: r.StatusCode}}, result...)
+	}
+	return result
 }
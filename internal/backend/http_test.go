@@ -1,6 +1,8 @@
 package backend_test
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -24,10 +26,74 @@ func TestBackend(t *testing.T) {
 
 	// execute http request
 	u, _ := url.Parse(testServer.URL)
-	reader, err := client.Do(u)
+	reader, err := client.Do(context.Background(), u)
 	require.Nil(t, err)
 
 	// read response
 	require.Nil(t, err)
-	require.Equal(t, "body", string(reader))
+	require.Equal(t, "body", string(reader.Body))
+	require.Equal(t, http.StatusOK, reader.StatusCode)
+	require.Equal(t, 4, reader.Size)
+	require.NotZero(t, reader.Timing.Connect)
+	require.NotZero(t, reader.Duration)
+}
+
+func TestBackend_redirects(t *testing.T) {
+	// generate a test server redirecting twice
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/moved", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler("/new", http.StatusFound))
+	mux.HandleFunc("/new", func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte("body"))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	// build backend
+	logger := zerolog.Nop()
+	client := backend.New(&logger)
+
+	// execute http request
+	u, _ := url.Parse(testServer.URL + "/old")
+	res, err := client.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, testServer.URL+"/new", res.URL.String())
+	require.Len(t, res.Redirects, 2)
+	require.Equal(t, testServer.URL+"/old", res.Redirects[0].URL.String())
+	require.Equal(t, http.StatusMovedPermanently, res.Redirects[0].StatusCode)
+	require.Equal(t, testServer.URL+"/moved", res.Redirects[1].URL.String())
+	require.Equal(t, http.StatusFound, res.Redirects[1].StatusCode)
+}
+
+func TestBackend_redirectLoop(t *testing.T) {
+	// generate a test server redirecting in a cycle
+	mux := http.NewServeMux()
+	mux.Handle("/a", http.RedirectHandler("/b", http.StatusFound))
+	mux.Handle("/b", http.RedirectHandler("/a", http.StatusFound))
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	// the loop is detected when the redirects return to a page already requested
+	logger := zerolog.Nop()
+	u, _ := url.Parse(testServer.URL + "/a")
+	res, err := backend.New(&logger).Do(context.Background(), u)
+	require.Nil(t, res)
+	require.True(t, errors.Is(err, backend.ErrRedirectLoop))
+}
+
+func TestBackend_serverError(t *testing.T) {
+	// generate a test server failing with a body
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = res.Write([]byte("maintenance"))
+	}))
+	defer testServer.Close()
+
+	// the body of server errors is read
+	logger := zerolog.Nop()
+	u, _ := url.Parse(testServer.URL)
+	res, err := backend.New(&logger).Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	require.Equal(t, "maintenance", string(res.Body))
 }
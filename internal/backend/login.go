@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pmdcosta/crawler/internal/scraper"
+)
+
+// ErrNoCookieJar is returned when logging in without a cookie jar to keep the session
+var ErrNoCookieJar = errors.New("a cookie jar is required to login")
+
+// Login authenticates the backend by submitting the login form found in the url
+// the form is filled with the provided fields on top of its default values, which include any csrf tokens,
+// and the session cookies are kept in the backend cookie jar for the following requests
+func (b *Http) Login(ctx context.Context, u *url.URL, fields map[string]string, selector string) error {
+	if b.client.Jar == nil {
+		return ErrNoCookieJar
+	}
+	b.logger.Debug().Str("url", u.String()).Msg("fetching login form")
+
+	// get the login page
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := b.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("login page returned %s", res.Status)
+	}
+	page, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	// fill the login form
+	form, err := scraper.ScrapeForm(res.Request.URL, page, selector)
+	if err != nil {
+		return err
+	}
+	for k, v := range fields {
+		form.Values.Set(k, v)
+	}
+
+	// submit the login form
+	if form.Method == http.MethodPost {
+		request, err = http.NewRequestWithContext(ctx, http.MethodPost, form.Action.String(), strings.NewReader(form.Values.Encode()))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		action := *form.Action
+		action.RawQuery = form.Values.Encode()
+		request, err = http.NewRequestWithContext(ctx, http.MethodGet, action.String(), nil)
+		if err != nil {
+			return err
+		}
+	}
+	request.Header.Set("Referer", res.Request.URL.String())
+	submit, err := b.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer submit.Body.Close()
+	if submit.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("login form returned %s", submit.Status)
+	}
+	b.logger.Info().Str("url", submit.Request.URL.String()).Int("code", submit.StatusCode).Msg("logged in")
+	return nil
+}
@@ -0,0 +1,63 @@
+package backend_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+const loginPage = `<html><body>
+<form action="/search"><input name="q"></form>
+<form action="/session" method="post">
+	<input type="hidden" name="csrf" value="token">
+	<input type="text" name="user">
+	<input type="password" name="pass">
+	<input type="submit" name="go" value="Login">
+</form>
+</body></html>`
+
+func TestBackend_login(t *testing.T) {
+	// generate a test server with a login form protecting the content
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte(loginPage))
+	})
+	mux.HandleFunc("/session", func(res http.ResponseWriter, req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Nil(t, req.ParseForm())
+		require.Equal(t, url.Values{"csrf": {"token"}, "user": {"admin"}, "pass": {"secret"}}, req.PostForm)
+		http.SetCookie(res, &http.Cookie{Name: "session", Value: "ok", Path: "/"})
+		http.Redirect(res, req, "/", http.StatusFound)
+	})
+	mux.HandleFunc("/", func(res http.ResponseWriter, req *http.Request) {
+		if c, err := req.Cookie("session"); err != nil || c.Value != "ok" {
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = res.Write([]byte("body"))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	// build backend
+	logger := zerolog.Nop()
+	jar, _ := cookiejar.New(nil)
+	client := backend.New(&logger, backend.SetCookieJar(jar))
+
+	// login
+	u, _ := url.Parse(testServer.URL + "/login")
+	require.Nil(t, client.Login(context.Background(), u, map[string]string{"user": "admin", "pass": "secret"}, ""))
+
+	// execute http request with the session
+	u, _ = url.Parse(testServer.URL)
+	body, err := client.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "body", string(body.Body))
+}
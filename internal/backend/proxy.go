@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrUnsupportedProxy is returned when the proxy scheme is not supported
+var ErrUnsupportedProxy = errors.New("unsupported proxy scheme, expected http, https or socks5")
+
+// ParseProxy parses and validates a proxy url
+func ParseProxy(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return u, nil
+	default:
+		return nil, ErrUnsupportedProxy
+	}
+}
+
+// SetProxy sets the proxy used for all requests without a matching proxy rule
+// by default the proxy is read from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+func SetProxy(proxy *url.URL) Option {
+	return func(b *Http) {
+		b.proxy = proxy
+		b.transport.Proxy = b.proxyFor
+	}
+}
+
+// AddProxyRule sets the proxy used for requests to a host and its subdomains
+// a nil proxy connects to the host directly
+func AddProxyRule(host string, proxy *url.URL) Option {
+	return func(b *Http) {
+		if b.proxyRules == nil {
+			b.proxyRules = make(map[string]*url.URL)
+		}
+		b.proxyRules[strings.ToLower(host)] = proxy
+		b.transport.Proxy = b.proxyFor
+	}
+}
+
+// proxyFor returns the proxy for a request, the most specific proxy rule matching the host is used
+// without a matching rule, the proxy set or the one from the environment is used
+func (b *Http) proxyFor(request *http.Request) (*url.URL, error) {
+	host := strings.ToLower(request.URL.Hostname())
+	var match string
+	var matched bool
+	for h := range b.proxyRules {
+		if host != h && !strings.HasSuffix(host, "."+h) {
+			continue
+		}
+		if !matched || len(h) > len(match) {
+			match, matched = h, true
+		}
+	}
+	if matched {
+		return b.proxyRules[match], nil
+	}
+	if b.proxy == nil {
+		return http.ProxyFromEnvironment(request)
+	}
+	return b.proxy, nil
+}
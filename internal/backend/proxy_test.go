@@ -0,0 +1,47 @@
+package backend_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_proxy(t *testing.T) {
+	// generate a test proxy that answers every request on behalf of the host
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte("proxy " + req.URL.Host))
+	}))
+	defer proxyServer.Close()
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte("direct"))
+	}))
+	defer testServer.Close()
+
+	// build backend
+	logger := zerolog.Nop()
+	proxy, err := backend.ParseProxy(proxyServer.URL)
+	require.Nil(t, err)
+	client := backend.New(&logger, backend.SetProxy(proxy), backend.AddProxyRule("127.0.0.1", nil))
+
+	// requests to hosts without a rule go through the proxy
+	u, _ := url.Parse("http://example.com")
+	body, err := client.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "proxy example.com", string(body.Body))
+
+	// requests to hosts with a direct rule skip the proxy
+	u, _ = url.Parse(testServer.URL)
+	body, err = client.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "direct", string(body.Body))
+
+	// unsupported proxies are rejected
+	_, err = backend.ParseProxy("ftp://proxy")
+	require.Equal(t, backend.ErrUnsupportedProxy, err)
+}
@@ -0,0 +1,84 @@
+package backend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// ErrInvalidTLSVersion is returned when the tls version is not known
+var ErrInvalidTLSVersion = errors.New("invalid tls version, expected 1.0, 1.1, 1.2 or 1.3")
+
+// tlsVersions maps the tls versions to their identifiers
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses a tls version such as 1.2
+func ParseTLSVersion(s string) (uint16, error) {
+	v, found := tlsVersions[s]
+	if !found {
+		return 0, ErrInvalidTLSVersion
+	}
+	return v, nil
+}
+
+// LoadCertPool returns the system cert pool extended with the certificates in the pem files
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, f := range files {
+		pem, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", f)
+		}
+	}
+	return pool, nil
+}
+
+// SetRootCAs sets the certificate authorities used to verify the servers
+func SetRootCAs(pool *x509.CertPool) Option {
+	return func(b *Http) {
+		b.tlsConfig().RootCAs = pool
+	}
+}
+
+// AddClientCertificate adds a client certificate presented to servers requiring mutual tls
+func AddClientCertificate(cert tls.Certificate) Option {
+	return func(b *Http) {
+		c := b.tlsConfig()
+		c.Certificates = append(c.Certificates, cert)
+	}
+}
+
+// SetInsecureSkipVerify disables the verification of the server certificates
+func SetInsecureSkipVerify(skip bool) Option {
+	return func(b *Http) {
+		b.tlsConfig().InsecureSkipVerify = skip
+	}
+}
+
+// SetMinTLSVersion sets the minimum tls version accepted
+func SetMinTLSVersion(v uint16) Option {
+	return func(b *Http) {
+		b.tlsConfig().MinVersion = v
+	}
+}
+
+// tlsConfig returns the tls configuration of the transport
+func (b *Http) tlsConfig() *tls.Config {
+	if b.transport.TLSClientConfig == nil {
+		b.transport.TLSClientConfig = &tls.Config{}
+	}
+	return b.transport.TLSClientConfig
+}
@@ -0,0 +1,41 @@
+package backend_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_tls(t *testing.T) {
+	// generate a tls test server with a self-signed certificate
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte("body"))
+	}))
+	defer testServer.Close()
+	u, _ := url.Parse(testServer.URL)
+	logger := zerolog.Nop()
+
+	// the certificate is not trusted by default
+	_, err := backend.New(&logger).Do(context.Background(), u)
+	require.NotNil(t, err)
+
+	// trust the server certificate
+	pool := x509.NewCertPool()
+	pool.AddCert(testServer.Certificate())
+	body, err := backend.New(&logger, backend.SetRootCAs(pool), backend.SetMinTLSVersion(tls.VersionTLS12)).Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "body", string(body.Body))
+
+	// skip the certificate verification
+	body, err = backend.New(&logger, backend.SetInsecureSkipVerify(true)).Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "body", string(body.Body))
+}
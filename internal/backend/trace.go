@@ -0,0 +1,90 @@
+package backend
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// tracer collects the time taken by each phase of a request
+// when following redirects, only the phases of the last request are kept
+type tracer struct {
+	mu     sync.Mutex
+	timing crawler.Timing
+	// address of the server of the last connection
+	remoteAddr string
+
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wrote        time.Time
+	firstByte    time.Time
+}
+
+// trace returns the hooks that collect the timing of a request
+func (t *tracer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.remoteAddr = info.Conn.RemoteAddr().String()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.measure(&t.timing.DNS, t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			t.measure(&t.timing.Connect, t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.measure(&t.timing.TLS, t.tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mark(&t.wrote)
+		},
+		GotFirstResponseByte: func() {
+			t.mark(&t.firstByte)
+			t.measure(&t.timing.TTFB, t.wrote)
+		},
+	}
+}
+
+// done adds the total duration, the timing of each phase and the server address to the response
+func (t *tracer) done(res *crawler.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.firstByte.IsZero() {
+		t.timing.Download = time.Since(t.firstByte)
+	}
+	res.Duration = time.Since(t.start)
+	res.Timing = t.timing
+	res.RemoteAddr = t.remoteAddr
+}
+
+// mark records the time a phase started
+func (t *tracer) mark(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*at = time.Now()
+}
+
+// measure records the time taken by a phase
+func (t *tracer) measure(d *time.Duration, start time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !start.IsZero() {
+		*d = time.Since(start)
+	}
+}
@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrHTTP2Unsupported is returned when http/2 is forced and the server does not support it
+var ErrHTTP2Unsupported = errors.New("server does not support http/2")
+
+// SetMaxIdleConns sets the maximum number of idle connections kept open across all hosts
+func SetMaxIdleConns(n int) Option {
+	return func(b *Http) {
+		b.transport.MaxIdleConns = n
+	}
+}
+
+// SetMaxIdleConnsPerHost sets the maximum number of idle connections kept open to each host
+func SetMaxIdleConnsPerHost(n int) Option {
+	return func(b *Http) {
+		b.transport.MaxIdleConnsPerHost = n
+	}
+}
+
+// SetIdleConnTimeout sets how long an idle connection is kept open
+func SetIdleConnTimeout(t time.Duration) Option {
+	return func(b *Http) {
+		b.transport.IdleConnTimeout = t
+	}
+}
+
+// SetKeepAlive sets the interval between tcp keep-alive probes, a negative interval disables the probes
+func SetKeepAlive(t time.Duration) Option {
+	return func(b *Http) {
+		b.dialer.KeepAlive = t
+	}
+}
+
+// DisableConnReuse opens a new connection for each request instead of reusing the idle connections
+func DisableConnReuse() Option {
+	return func(b *Http) {
+		b.transport.DisableKeepAlives = true
+	}
+}
+
+// SetDialTimeout sets the maximum time to establish a tcp connection
+func SetDialTimeout(t time.Duration) Option {
+	return func(b *Http) {
+		b.dialer.Timeout = t
+	}
+}
+
+// SetTLSHandshakeTimeout sets the maximum time to perform the tls handshake
+func SetTLSHandshakeTimeout(t time.Duration) Option {
+	return func(b *Http) {
+		b.transport.TLSHandshakeTimeout = t
+	}
+}
+
+// SetResponseHeaderTimeout sets the maximum time to wait for the response headers after sending the request
+func SetResponseHeaderTimeout(t time.Duration) Option {
+	return func(b *Http) {
+		b.transport.ResponseHeaderTimeout = t
+	}
+}
+
+// ForceHTTP1 disables http/2 and only uses http/1.1
+func ForceHTTP1() Option {
+	return func(b *Http) {
+		b.http2 = false
+		b.transport.ForceAttemptHTTP2 = false
+		b.transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		b.tlsConfig().NextProtos = []string{"http/1.1"}
+	}
+}
+
+// ForceHTTP2 fails the requests to servers that do not support http/2
+func ForceHTTP2() Option {
+	return func(b *Http) {
+		b.http2 = true
+		b.transport.ForceAttemptHTTP2 = true
+		b.transport.TLSNextProto = nil
+		if b.transport.TLSClientConfig != nil {
+			b.transport.TLSClientConfig.NextProtos = nil
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package backend_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackend_transport(t *testing.T) {
+	// generate a tls test server supporting http/2, negotiated with alpn as EnableHTTP2 requires go 1.14
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte(req.Proto))
+	}))
+	testServer.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	testServer.StartTLS()
+	defer testServer.Close()
+	u, _ := url.Parse(testServer.URL)
+	logger := zerolog.Nop()
+	pool := x509.NewCertPool()
+	pool.AddCert(testServer.Certificate())
+	options := []backend.Option{
+		backend.SetRootCAs(pool),
+		backend.SetMaxIdleConnsPerHost(10),
+		backend.SetDialTimeout(time.Second),
+		backend.SetTLSHandshakeTimeout(time.Second),
+		backend.SetResponseHeaderTimeout(time.Second),
+	}
+
+	// force http/2
+	res, err := backend.New(&logger, append(options, backend.ForceHTTP2())...).Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "HTTP/2.0", string(res.Body))
+	require.NotZero(t, res.Timing.TLS)
+
+	// force http/1.1
+	res, err = backend.New(&logger, append(options, backend.ForceHTTP1())...).Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "HTTP/1.1", string(res.Body))
+
+	// servers without http/2 are rejected when forcing it
+	plainServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer plainServer.Close()
+	u, _ = url.Parse(plainServer.URL)
+	_, err = backend.New(&logger, backend.ForceHTTP2()).Do(context.Background(), u)
+	require.Equal(t, backend.ErrHTTP2Unsupported, err)
+}
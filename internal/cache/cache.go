@@ -0,0 +1,291 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/fsutil"
+	"github.com/rs/zerolog"
+)
+
+// ErrNotCached is returned in offline mode when the page is not in the cache, or is stale and must be revalidated
+var ErrNotCached = errors.New("page not in cache")
+
+// Cache is a backend that keeps the fetched pages on disk and revalidates them on recrawls
+type Cache struct {
+	logger *zerolog.Logger
+
+	// directory where the pages are stored
+	dir string
+	// backend used to fetch the pages
+	backend Backend
+
+	// whether to serve the pages only from the cache
+	offline bool
+}
+
+// Backend defines the backend used to fetch and revalidate the pages
+type Backend interface {
+	DoWithHeader(ctx context.Context, u *url.URL, header http.Header) (*crawler.Response, error)
+}
+
+// Option is an optimal configuration option that can be applied to a cache
+type Option func(c *Cache)
+
+// entry is the metadata of a cached page, the body is stored in a separate file
+type entry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	// time the page was stored or last revalidated
+	Stored time.Time `json:"stored"`
+	// time the page was fetched from the server
+	Fetched time.Time `json:"fetched"`
+}
+
+// New instantiates a new cache stored in the directory
+func New(logger *zerolog.Logger, dir string, backend Backend, opts ...Option) *Cache {
+	l := logger.With().Str("pkg", "cache").Logger()
+	c := Cache{
+		logger:  &l,
+		dir:     dir,
+		backend: backend,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
+
+// SetOffline serves the pages only from the cache, without making any requests
+func SetOffline(offline bool) Option {
+	return func(c *Cache) {
+		c.offline = offline
+	}
+}
+
+// Do returns the page from the cache if it is still fresh, otherwise the page is fetched or revalidated
+// the responses served from the cache keep their original status code and are marked with how they were served
+// the cache is shared by all the crawls, so private responses are not stored
+func (c *Cache) Do(ctx context.Context, u *url.URL) (*crawler.Response, error) {
+	start := time.Now()
+	key := c.key(u)
+	e, body, err := c.load(key)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("url", u.String()).Msg("failed to read cached page")
+	}
+
+	// serve from the cache
+	if c.offline {
+		if e == nil || (e.mustRevalidate() && !e.fresh(start)) {
+			return nil, ErrNotCached
+		}
+		return e.response(body, start), nil
+	}
+	if e != nil && e.fresh(start) {
+		c.logger.Debug().Str("url", u.String()).Msg("serving fresh page from cache")
+		return e.response(body, start), nil
+	}
+
+	// revalidate the cached page
+	var header http.Header
+	if e != nil {
+		header = make(http.Header)
+		if etag := e.Header.Get("ETag"); etag != "" {
+			header.Set("If-None-Match", etag)
+		}
+		if modified := e.Header.Get("Last-Modified"); modified != "" {
+			header.Set("If-Modified-Since", modified)
+		}
+	}
+	res, err := c.backend.DoWithHeader(ctx, u, header)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotModified && e != nil {
+		c.logger.Debug().Str("url", u.String()).Msg("cached page not modified")
+		for k, v := range res.Header {
+			e.Header[k] = v
+		}
+		e.Stored = time.Now()
+		if err := c.store(key, e, nil); err != nil {
+			c.logger.Warn().Err(err).Str("url", u.String()).Msg("failed to cache page")
+		}
+		cached := *res
+		cached.StatusCode = e.StatusCode
+		cached.Header = e.Header
+		cached.Body = body
+		cached.Size = len(body)
+		cached.Cache = crawler.CacheRevalidated
+		cached.Cached = e.fetched()
+		return &cached, nil
+	}
+
+	// store the new page
+	if storable(res) {
+		final := u
+		if res.URL != nil {
+			final = res.URL
+		}
+		now := time.Now()
+		e = &entry{URL: final.String(), StatusCode: res.StatusCode, Header: res.Header, Stored: now, Fetched: now}
+		if err := c.store(key, e, res.Body); err != nil {
+			c.logger.Warn().Err(err).Str("url", u.String()).Msg("failed to cache page")
+		}
+	}
+	return res, nil
+}
+
+// key returns the file name of a cached page
+func (c *Cache) key(u *url.URL) string {
+	h := sha256.Sum256([]byte(u.String()))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:]))
+}
+
+// load reads a cached page, nil is returned if the page is not cached
+func (c *Cache) load(key string) (*entry, []byte, error) {
+	meta, err := ioutil.ReadFile(key + ".json")
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(meta, &e); err != nil {
+		return nil, nil, err
+	}
+	body, err := ioutil.ReadFile(key + ".body")
+	if err != nil {
+		return nil, nil, err
+	}
+	return &e, body, nil
+}
+
+// store writes a page to the cache, the body is only written if not nil
+func (c *Cache) store(key string, e *entry, body []byte) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	if body != nil {
+		err := fsutil.WriteFile(key+".body", func(w io.Writer) error {
+			_, err := w.Write(body)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	meta, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(key+".json", func(w io.Writer) error {
+		_, err := w.Write(meta)
+		return err
+	})
+}
+
+// response returns the cached page as a fresh cache hit
+func (e *entry) response(body []byte, start time.Time) *crawler.Response {
+	u, _ := url.Parse(e.URL)
+	return &crawler.Response{
+		URL:        u,
+		StatusCode: e.StatusCode,
+		Header:     e.Header,
+		Body:       body,
+		Size:       len(body),
+		Time:       start,
+		Duration:   time.Since(start),
+		Cache:      crawler.CacheHit,
+		Cached:     e.fetched(),
+	}
+}
+
+// fetched returns the time the page was fetched, entries stored before it was recorded use the stored time
+func (e *entry) fetched() time.Time {
+	if e.Fetched.IsZero() {
+		return e.Stored
+	}
+	return e.Fetched
+}
+
+// fresh checks if the cached page can be used without revalidating it
+// the age of the page includes the Age header it was stored with, and s-maxage takes precedence as the cache is shared
+func (e *entry) fresh(now time.Time) bool {
+	cc := cacheControl(e.Header)
+	if _, found := cc["no-cache"]; found {
+		return false
+	}
+	age := now.Sub(e.Stored)
+	if seconds, err := strconv.Atoi(e.Header.Get("Age")); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if maxAge, found := cc[directive]; found {
+			seconds, err := strconv.Atoi(maxAge)
+			if err != nil {
+				return false
+			}
+			return age < time.Duration(seconds)*time.Second
+		}
+	}
+	if expires := e.Header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return false
+		}
+		return now.Before(t)
+	}
+	return false
+}
+
+// mustRevalidate checks if the page must not be served once stale
+func (e *entry) mustRevalidate() bool {
+	cc := cacheControl(e.Header)
+	_, must := cc["must-revalidate"]
+	_, proxy := cc["proxy-revalidate"]
+	return must || proxy
+}
+
+// storable checks if the response can be cached
+func storable(res *crawler.Response) bool {
+	if res.StatusCode >= http.StatusInternalServerError || res.StatusCode == http.StatusNotModified {
+		return false
+	}
+	cc := cacheControl(res.Header)
+	_, noStore := cc["no-store"]
+	_, private := cc["private"]
+	return !noStore && !private
+}
+
+// cacheControl parses the Cache-Control header directives
+func cacheControl(h http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, v := range h["Cache-Control"] {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(d)
+			if d == "" {
+				continue
+			}
+			name, value := d, ""
+			if i := strings.Index(d, "="); i >= 0 {
+				name, value = d[:i], strings.Trim(d[i+1:], `"`)
+			}
+			directives[strings.ToLower(name)] = value
+		}
+	}
+	return directives
+}
@@ -0,0 +1,104 @@
+package cache_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/pmdcosta/crawler/internal/cache"
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	// generate a test server serving a page with validators
+	var requests, revalidated int
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests++
+		switch req.URL.Path {
+		case "/fresh":
+			res.Header().Set("Cache-Control", "max-age=3600")
+		case "/shared":
+			res.Header().Set("Cache-Control", "max-age=0, s-maxage=3600")
+		case "/aged":
+			res.Header().Set("Cache-Control", "max-age=3600, must-revalidate")
+			res.Header().Set("Age", "3600")
+		case "/private":
+			res.Header().Set("Cache-Control", "private, max-age=3600")
+		}
+		res.Header().Set("ETag", `"v1"`)
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			revalidated++
+			res.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = res.Write([]byte("body"))
+	}))
+	defer testServer.Close()
+
+	dir, err := ioutil.TempDir("", "cache")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := zerolog.Nop()
+	c := cache.New(&logger, dir, backend.New(&logger))
+
+	// the first request stores the page
+	u, _ := url.Parse(testServer.URL)
+	res, err := c.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "body", string(res.Body))
+
+	// the recrawl revalidates the page and reuses the cached body
+	res, err = c.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, crawler.CacheRevalidated, res.Cache)
+	require.Equal(t, "body", string(res.Body))
+	require.Equal(t, 2, requests)
+	require.Equal(t, 1, revalidated)
+
+	// fresh pages are not requested again
+	fresh, _ := url.Parse(testServer.URL + "/fresh")
+	_, err = c.Do(context.Background(), fresh)
+	require.Nil(t, err)
+	res, err = c.Do(context.Background(), fresh)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, crawler.CacheHit, res.Cache)
+	require.Equal(t, "body", string(res.Body))
+	require.NotZero(t, res.Duration)
+	require.Equal(t, 3, requests)
+
+	// s-maxage takes precedence, the Age header is part of the age and private pages are not stored
+	for _, p := range []string{"/shared", "/aged", "/private"} {
+		page, _ := url.Parse(testServer.URL + p)
+		_, err = c.Do(context.Background(), page)
+		require.Nil(t, err)
+		res, err = c.Do(context.Background(), page)
+		require.Nil(t, err)
+		require.Equal(t, p == "/shared", res.Cache == crawler.CacheHit, p)
+	}
+	require.Equal(t, 8, requests)
+
+	// offline mode only serves from the cache
+	offline := cache.New(&logger, dir, backend.New(&logger), cache.SetOffline(true))
+	res, err = offline.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "body", string(res.Body))
+	missing, _ := url.Parse(testServer.URL + "/missing")
+	_, err = offline.Do(context.Background(), missing)
+	require.Equal(t, cache.ErrNotCached, err)
+
+	// stale pages that must be revalidated are not served offline
+	aged, _ := url.Parse(testServer.URL + "/aged")
+	_, err = offline.Do(context.Background(), aged)
+	require.Equal(t, cache.ErrNotCached, err)
+	require.Equal(t, 8, requests)
+}
@@ -0,0 +1,67 @@
+package crawler
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// how a response was served from the cache
+const (
+	// CacheHit is a fresh response served from the cache without any request
+	CacheHit = "hit"
+	// CacheRevalidated is a cached response the server confirmed was not modified
+	CacheRevalidated = "revalidated"
+)
+
+// Response of fetching a task
+type Response struct {
+	// url of the response after following redirects
+	URL *url.URL
+	// redirects followed to reach the url, in order
+	Redirects  []Redirect
+	StatusCode int
+	Header     http.Header
+	// body of the response, it is released once the task is processed
+	Body []byte
+	// size of the body in bytes
+	Size int
+	// time the request was sent
+	Time time.Time
+	// total time taken to fetch the response
+	Duration time.Duration
+	// time taken by each phase of the request
+	Timing Timing
+
+	// method and headers of the request
+	Method        string
+	RequestHeader http.Header
+	// address of the server that sent the response
+	RemoteAddr string
+
+	// how the response was served from the cache, empty if it was fetched
+	Cache string
+	// time the cached response was fetched from the server
+	Cached time.Time
+}
+
+// Redirect is a redirect response followed while fetching a task
+type Redirect struct {
+	// url that was redirected
+	URL        *url.URL
+	StatusCode int
+}
+
+// Timing of each phase of a request
+type Timing struct {
+	// time resolving the host name
+	DNS time.Duration
+	// time establishing the tcp connection
+	Connect time.Duration
+	// time performing the tls handshake
+	TLS time.Duration
+	// time waiting for the first byte of the response after sending the request
+	TTFB time.Duration
+	// time reading the response body
+	Download time.Duration
+}
@@ -14,4 +14,9 @@ type TaskResult struct {
 	Task
 	Children map[string]int
 	Error    *error
+	Response *Response
+	// title of the html page
+	Title string
+	// anchor texts of each link
+	Anchors map[string][]string
 }
@@ -0,0 +1,230 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pmdcosta/crawler/internal/export"
+)
+
+// Diff is the changes between two crawls
+type Diff struct {
+	// pages only crawled in the new crawl
+	Added []string `json:"added"`
+	// pages only crawled in the old crawl
+	Removed []string `json:"removed"`
+	// pages crawled in both with different links
+	Links []LinkChange `json:"links"`
+	// pages crawled in both with a different status code
+	Status []StatusChange `json:"status"`
+	// pages crawled in both redirected differently
+	Redirects []RedirectChange `json:"redirects"`
+	// pages crawled and linked from other pages in the old crawl, and not linked from any page in the new crawl
+	Orphaned []string `json:"orphaned"`
+}
+
+// LinkChange is the links added and removed from a page
+type LinkChange struct {
+	URL     string   `json:"url"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// StatusChange is the change of the status code of a page
+type StatusChange struct {
+	URL string `json:"url"`
+	Old int    `json:"old"`
+	New int    `json:"new"`
+}
+
+// RedirectChange is the change of the redirects of a page, a redirect is described by its hops and final url
+type RedirectChange struct {
+	URL string `json:"url"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// Compare compares two crawls, status codes and redirects are only compared if both crawls saved them
+func Compare(old, current *export.Crawl) *Diff {
+	d := Diff{
+		Added:     []string{},
+		Removed:   []string{},
+		Links:     []LinkChange{},
+		Status:    []StatusChange{},
+		Redirects: []RedirectChange{},
+		Orphaned:  []string{},
+	}
+	oldPages, newPages := index(old), index(current)
+
+	for _, u := range urls(current) {
+		if _, found := oldPages[u]; !found {
+			d.Added = append(d.Added, u)
+		}
+	}
+	for _, u := range urls(old) {
+		o := oldPages[u]
+		n, found := newPages[u]
+		if !found {
+			d.Removed = append(d.Removed, u)
+			continue
+		}
+
+		// links
+		c := LinkChange{URL: u, Added: []string{}, Removed: []string{}}
+		for _, l := range sortedLinks(n.Links) {
+			if _, found := o.Links[l]; !found {
+				c.Added = append(c.Added, l)
+			}
+		}
+		for _, l := range sortedLinks(o.Links) {
+			if _, found := n.Links[l]; !found {
+				c.Removed = append(c.Removed, l)
+			}
+		}
+		if len(c.Added) != 0 || len(c.Removed) != 0 {
+			d.Links = append(d.Links, c)
+		}
+
+		// status and redirects are not saved by the json output
+		if old.Version == 0 || current.Version == 0 {
+			continue
+		}
+		if o.Status != n.Status {
+			d.Status = append(d.Status, StatusChange{URL: u, Old: o.Status, New: n.Status})
+		}
+		if redirect(o) != redirect(n) {
+			d.Redirects = append(d.Redirects, RedirectChange{URL: u, Old: redirect(o), New: redirect(n)})
+		}
+	}
+
+	// orphaned pages
+	oldLinked, newLinked := linked(old), linked(current)
+	for _, u := range urls(old) {
+		_, before := oldLinked[u]
+		_, after := newLinked[u]
+		if before && !after {
+			d.Orphaned = append(d.Orphaned, u)
+		}
+	}
+	return &d
+}
+
+// WriteJSON writes the diff as json
+func (d *Diff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// WriteText writes a human readable version of the diff
+func (d *Diff) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d pages added, %d removed, %d with link changes, %d status changes, %d redirect changes, %d orphaned\n",
+		len(d.Added), len(d.Removed), len(d.Links), len(d.Status), len(d.Redirects), len(d.Orphaned))
+	section(&b, "Added pages", d.Added, "+ ")
+	section(&b, "Removed pages", d.Removed, "- ")
+	if len(d.Links) != 0 {
+		b.WriteString("\nLink changes\n")
+		for _, c := range d.Links {
+			fmt.Fprintf(&b, "  %s\n", c.URL)
+			for _, l := range c.Added {
+				fmt.Fprintf(&b, "    + %s\n", l)
+			}
+			for _, l := range c.Removed {
+				fmt.Fprintf(&b, "    - %s\n", l)
+			}
+		}
+	}
+	if len(d.Status) != 0 {
+		b.WriteString("\nStatus changes\n")
+		for _, c := range d.Status {
+			fmt.Fprintf(&b, "  %s %d -> %d\n", c.URL, c.Old, c.New)
+		}
+	}
+	if len(d.Redirects) != 0 {
+		b.WriteString("\nRedirect changes\n")
+		for _, c := range d.Redirects {
+			fmt.Fprintf(&b, "  %s\n    - %s\n    + %s\n", c.URL, orNone(c.Old), orNone(c.New))
+		}
+	}
+	section(&b, "Orphaned pages", d.Orphaned, "  ")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Empty checks if the crawls are the same
+func (d *Diff) Empty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Links)+len(d.Status)+len(d.Redirects)+len(d.Orphaned) == 0
+}
+
+// section writes a list of urls under a title
+func section(b *strings.Builder, title string, urls []string, prefix string) {
+	if len(urls) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s\n", title)
+	for _, u := range urls {
+		fmt.Fprintf(b, "  %s%s\n", prefix, u)
+	}
+}
+
+// orNone describes an empty redirect
+func orNone(s string) string {
+	if s == "" {
+		return "no redirect"
+	}
+	return s
+}
+
+// index indexes the pages of a crawl by url
+func index(c *export.Crawl) map[string]export.Record {
+	var result = make(map[string]export.Record)
+	for _, p := range c.Pages {
+		result[p.URL] = p
+	}
+	return result
+}
+
+// redirect describes the redirects of a page, or an empty string if it was not redirected
+func redirect(r export.Record) string {
+	if len(r.Redirects) == 0 && r.FinalURL == "" {
+		return ""
+	}
+	return strings.Join(append(append([]string{}, r.Redirects...), r.FinalURL), " -> ")
+}
+
+// linked returns the pages linked from another page of the crawl
+func linked(c *export.Crawl) map[string]struct{} {
+	var result = make(map[string]struct{})
+	for _, p := range c.Pages {
+		for l := range p.Links {
+			if l != p.URL {
+				result[l] = struct{}{}
+			}
+		}
+	}
+	return result
+}
+
+// urls returns the urls of the pages of a crawl sorted
+func urls(c *export.Crawl) []string {
+	var result []string
+	for _, p := range c.Pages {
+		result = append(result, p.URL)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// sortedLinks returns the links of a page sorted
+func sortedLinks(links map[string]int) []string {
+	var result []string
+	for l := range links {
+		result = append(result, l)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,64 @@
+package diff_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/diff"
+	"github.com/pmdcosta/crawler/internal/export"
+	"github.com/stretchr/testify/require"
+)
+
+const oldCrawl = `{"version": 1, "seed": "http://google.com", "pages": [
+	{"url": "http://google.com", "depth": 0, "status": 200, "links": {"http://google.com/a": 1, "http://google.com/b": 1}},
+	{"url": "http://google.com/a", "depth": 1, "status": 200, "links": {}},
+	{"url": "http://google.com/b", "depth": 1, "status": 200, "final_url": "http://google.com/b/", "redirects": ["http://google.com/b"], "links": {}}
+]}`
+
+const newCrawl = `{"version": 1, "seed": "http://google.com", "pages": [
+	{"url": "http://google.com", "depth": 0, "status": 200, "links": {"http://google.com/b": 1, "http://google.com/c": 1}},
+	{"url": "http://google.com/b", "depth": 1, "status": 404, "links": {}},
+	{"url": "http://google.com/c", "depth": 1, "status": 200, "links": {}}
+]}`
+
+func readCrawl(t *testing.T, s string) *export.Crawl {
+	c, err := export.ReadCrawl(strings.NewReader(s))
+	require.Nil(t, err)
+	return c
+}
+
+func TestDiff(t *testing.T) {
+	d := diff.Compare(readCrawl(t, oldCrawl), readCrawl(t, newCrawl))
+	require.Equal(t, &diff.Diff{
+		Added:     []string{"http://google.com/c"},
+		Removed:   []string{"http://google.com/a"},
+		Links:     []diff.LinkChange{{URL: "http://google.com", Added: []string{"http://google.com/c"}, Removed: []string{"http://google.com/a"}}},
+		Status:    []diff.StatusChange{{URL: "http://google.com/b", Old: 200, New: 404}},
+		Redirects: []diff.RedirectChange{{URL: "http://google.com/b", Old: "http://google.com/b -> http://google.com/b/", New: ""}},
+		Orphaned:  []string{"http://google.com/a"},
+	}, d)
+	require.False(t, d.Empty())
+
+	var buf bytes.Buffer
+	require.Nil(t, d.WriteText(&buf))
+	require.Contains(t, buf.String(), "1 pages added, 1 removed, 1 with link changes, 1 status changes, 1 redirect changes, 1 orphaned\n")
+	require.Contains(t, buf.String(), "\nStatus changes\n  http://google.com/b 200 -> 404\n")
+
+	buf.Reset()
+	require.Nil(t, d.WriteJSON(&buf))
+	require.Contains(t, buf.String(), `"old": 200`)
+}
+
+func TestDiff_json(t *testing.T) {
+	// the json output has no status codes
+	old := readCrawl(t, `{"http://google.com": {"http://google.com/a": 1}, "http://google.com/a": {}}`)
+	d := diff.Compare(old, readCrawl(t, newCrawl))
+	require.Equal(t, []string{"http://google.com/a"}, d.Removed)
+	require.Empty(t, d.Status)
+
+	require.True(t, diff.Compare(old, old).Empty())
+
+	_, err := export.ReadCrawl(strings.NewReader(`[1, 2]`))
+	require.Equal(t, export.ErrInvalidCrawl, err)
+}
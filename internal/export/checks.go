@@ -0,0 +1,133 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// checks run over the crawled pages
+const (
+	CheckBrokenLink    = "broken-link"
+	CheckFetchError    = "fetch-error"
+	CheckRedirectLoop  = "redirect-loop"
+	CheckRedirectChain = "redirect-chain"
+	CheckSlowPage      = "slow-page"
+	CheckCrawlerTrap   = "crawler-trap"
+)
+
+// severities of the findings, from the most to the least severe
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNote    = "note"
+)
+
+// ErrInvalidSeverity is returned when the severity is not known
+var ErrInvalidSeverity = errors.New("invalid severity, expected error, warning or note")
+
+// severities ranks the severities, higher is more severe
+var severities = map[string]int{SeverityNote: 1, SeverityWarning: 2, SeverityError: 3}
+
+// checkDescriptions describes each check
+var checkDescriptions = map[string]string{
+	CheckBrokenLink:    "The page returned a client or server error status code.",
+	CheckFetchError:    "The page could not be fetched.",
+	CheckRedirectLoop:  "The page redirects in a loop or through too many redirects.",
+	CheckRedirectChain: "The page is only reached through more than one redirect.",
+	CheckSlowPage:      "The page took longer than the threshold to be fetched.",
+	CheckCrawlerTrap:   "The links matching the url pattern were skipped as a crawler trap.",
+}
+
+// Finding is a problem found by a check on a crawled page
+type Finding struct {
+	Check    string
+	Severity string
+	URL      string
+	Message  string
+	// pages linking to the url
+	Referrers []string
+}
+
+// ParseSeverity validates a severity
+func ParseSeverity(s string) (string, error) {
+	if _, found := severities[s]; !found {
+		return "", ErrInvalidSeverity
+	}
+	return s, nil
+}
+
+// Exceeds checks if any of the findings is at least as severe as the threshold
+func Exceeds(findings []Finding, threshold string) bool {
+	for _, f := range findings {
+		if severities[f.Severity] >= severities[threshold] {
+			return true
+		}
+	}
+	return false
+}
+
+// TrapFinding reports an url pattern skipped as a crawler trap, the url is the link that triggered the detection
+func TrapFinding(pattern, reason, u string, skipped int) Finding {
+	return Finding{
+		Check:    CheckCrawlerTrap,
+		Severity: SeverityWarning,
+		URL:      u,
+		Message:  fmt.Sprintf("%d links matching %s skipped, detected by %s", skipped, pattern, reason),
+	}
+}
+
+// Check runs the checks over the pages, pages taking longer than slow to be fetched are reported
+// a zero slow disables the slow page check
+func Check(pages []crawler.TaskResult, slow time.Duration) []Finding {
+	var referrers = make(map[string][]string)
+	for _, p := range pages {
+		for c := range p.Children {
+			if parsed, err := url.Parse(c); err == nil {
+				c = parsed.String()
+			}
+			referrers[c] = append(referrers[c], p.URL.String())
+		}
+	}
+
+	var findings []Finding
+	for _, p := range pages {
+		u := p.URL.String()
+		add := func(check, severity, message string) {
+			refs := referrers[u]
+			sort.Strings(refs)
+			findings = append(findings, Finding{Check: check, Severity: severity, URL: u, Message: message, Referrers: refs})
+		}
+
+		if p.Error != nil && *p.Error != nil {
+			if errors.Is(*p.Error, backend.ErrRedirectLoop) {
+				add(CheckRedirectLoop, SeverityError, (*p.Error).Error())
+			} else if p.Response == nil {
+				add(CheckFetchError, SeverityError, (*p.Error).Error())
+			}
+		}
+		if p.Response == nil {
+			continue
+		}
+		if p.Response.StatusCode >= 400 {
+			add(CheckBrokenLink, SeverityError, fmt.Sprintf("status code %d", p.Response.StatusCode))
+		}
+		if len(p.Response.Redirects) > 1 {
+			var hops []string
+			for _, r := range p.Response.Redirects {
+				hops = append(hops, fmt.Sprintf("%s (%d)", r.URL, r.StatusCode))
+			}
+			add(CheckRedirectChain, SeverityWarning, "redirected through "+strings.Join(hops, " -> ")+" to "+p.Response.URL.String())
+		}
+		if slow != 0 && p.Response.Duration > slow {
+			add(CheckSlowPage, SeverityWarning, fmt.Sprintf("fetched in %s, over %s", p.Response.Duration.Round(time.Millisecond), slow))
+		}
+	}
+	return findings
+}
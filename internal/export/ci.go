@@ -0,0 +1,177 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// junitSuites is the root element of a junit report
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Time      string         `xml:"time,attr"`
+	Failures  []junitFailure `xml:"failure"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Details string `xml:",chardata"`
+}
+
+// WriteJUnit writes a junit report with a test case for each page
+// findings at least as severe as the threshold fail the test case, the others are reported as its output
+func WriteJUnit(w io.Writer, seed string, pages []crawler.TaskResult, findings []Finding, threshold string) error {
+	var byURL = make(map[string][]Finding)
+	for _, f := range findings {
+		byURL[f.URL] = append(byURL[f.URL], f)
+	}
+
+	suite := junitSuite{Name: seed}
+	var total float64
+	for _, p := range pages {
+		u := p.URL.String()
+		c := junitCase{Name: u, ClassName: "crawl", Time: "0.000"}
+		if p.Response != nil {
+			c.Time = fmt.Sprintf("%.3f", p.Response.Duration.Seconds())
+			total += p.Response.Duration.Seconds()
+		}
+		var output []string
+		for _, f := range byURL[u] {
+			if severities[f.Severity] < severities[threshold] {
+				output = append(output, f.Severity+" "+f.Check+": "+f.Message)
+				continue
+			}
+			details := f.Message
+			if len(f.Referrers) != 0 {
+				details += "\nlinked from:\n" + strings.Join(f.Referrers, "\n")
+			}
+			c.Failures = append(c.Failures, junitFailure{Type: f.Check, Message: f.Message, Details: details})
+		}
+		c.SystemOut = strings.Join(output, "\n")
+		if len(c.Failures) != 0 {
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Time = fmt.Sprintf("%.3f", total)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Name: "crawl", Tests: suite.Tests, Failures: suite.Failures, Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// sarifLog is the root object of a sarif report
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               int `json:"id,omitempty"`
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+// WriteSARIF writes a sarif report with a result for each finding, the pages linking to the url are related locations
+func WriteSARIF(w io.Writer, findings []Finding) error {
+	var run sarifRun
+	run.Tool.Driver.Name = "crawler"
+	run.Tool.Driver.InformationURI = "https://github.com/pmdcosta/crawler"
+	var checks []string
+	for c := range checkDescriptions {
+		checks = append(checks, c)
+	}
+	sort.Strings(checks)
+	for _, c := range checks {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: c, ShortDescription: sarifMessage{Text: checkDescriptions[c]}})
+	}
+
+	run.Results = []sarifResult{}
+	for _, f := range findings {
+		r := sarifResult{RuleID: f.Check, Level: f.Severity, Message: sarifMessage{Text: f.Message}}
+		r.Locations = []sarifLocation{sarifLocationOf(f.URL, 0)}
+		for i, ref := range f.Referrers {
+			r.RelatedLocations = append(r.RelatedLocations, sarifLocationOf(ref, i+1))
+		}
+		run.Results = append(run.Results, r)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifLocationOf returns the location of an url
+func sarifLocationOf(u string, id int) sarifLocation {
+	var l sarifLocation
+	l.ID = id
+	l.PhysicalLocation.ArtifactLocation.URI = u
+	return l
+}
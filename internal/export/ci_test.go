@@ -0,0 +1,96 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/export"
+	"github.com/stretchr/testify/require"
+)
+
+// newCheckPages returns pages with a problem for each check
+func newCheckPages() []crawler.TaskResult {
+	pages := newPages()
+	loop, _ := url.Parse("http://google.com/loop")
+	slow, _ := url.Parse("http://google.com/slow")
+	a, _ := url.Parse("http://google.com/a")
+	b, _ := url.Parse("http://google.com/b")
+	err := error(&url.Error{Op: "Get", URL: "http://google.com/loop", Err: fmt.Errorf("%w: stopped after 10 redirects", backend.ErrRedirectLoop)})
+	return append(pages,
+		crawler.TaskResult{Task: crawler.Task{URL: loop, Tries: 4}, Error: &err},
+		crawler.TaskResult{Task: crawler.Task{URL: slow, Tries: 1}, Response: &crawler.Response{
+			URL: slow, StatusCode: 404, Duration: 3 * time.Second, Redirects: []crawler.Redirect{{URL: a, StatusCode: 301}, {URL: b, StatusCode: 302}},
+		}},
+	)
+}
+
+func TestChecks(t *testing.T) {
+	findings := export.Check(newCheckPages(), 2*time.Second)
+	var checks []string
+	for _, f := range findings {
+		checks = append(checks, f.URL+" "+f.Check+" "+f.Severity)
+	}
+	require.Equal(t, []string{
+		"http://google.com/broken fetch-error error",
+		"http://google.com/loop redirect-loop error",
+		"http://google.com/slow broken-link error",
+		"http://google.com/slow redirect-chain warning",
+		"http://google.com/slow slow-page warning",
+	}, checks)
+	require.Equal(t, []string{"http://google.com"}, findings[0].Referrers)
+
+	require.True(t, export.Exceeds(findings, export.SeverityError))
+	require.False(t, export.Exceeds(findings[3:], export.SeverityError))
+	require.True(t, export.Exceeds(findings[3:], export.SeverityNote))
+	_, err := export.ParseSeverity("fatal")
+	require.Equal(t, export.ErrInvalidSeverity, err)
+}
+
+func TestJUnit(t *testing.T) {
+	pages := newCheckPages()
+	var buf bytes.Buffer
+	require.Nil(t, export.WriteJUnit(&buf, "http://google.com", pages, export.Check(pages, 2*time.Second), export.SeverityError))
+	report := buf.String()
+	require.Contains(t, report, `<testsuites name="crawl" tests="4" failures="3">`)
+	require.Contains(t, report, `<testcase name="http://google.com" classname="crawl" time="0.002"></testcase>`)
+	require.Contains(t, report, `<failure type="fetch-error" message="connection refused">connection refused&#xA;linked from:&#xA;http://google.com</failure>`)
+	require.Contains(t, report, `<system-out>warning redirect-chain: redirected through http://google.com/a (301) -&gt; http://google.com/b (302) to http://google.com/slow&#xA;warning slow-page: fetched in 3s, over 2s</system-out>`)
+}
+
+func TestSARIF(t *testing.T) {
+	pages := newCheckPages()
+	var buf bytes.Buffer
+	require.Nil(t, export.WriteSARIF(&buf, export.Check(pages, 0)))
+
+	var report struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string
+						}
+					}
+				}
+				RelatedLocations []interface{}
+			}
+		}
+	}
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &report))
+	require.Equal(t, "2.1.0", report.Version)
+	results := report.Runs[0].Results
+	require.Len(t, results, 4)
+	require.Equal(t, "fetch-error", results[0].RuleID)
+	require.Equal(t, "error", results[0].Level)
+	require.Equal(t, "http://google.com/broken", results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	require.Len(t, results[0].RelatedLocations, 1)
+}
@@ -0,0 +1,118 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// CrawlVersion is the version of the saved crawl format
+const CrawlVersion = 1
+
+// ErrInvalidCrawl is returned when a saved crawl can not be read
+var ErrInvalidCrawl = errors.New("invalid saved crawl, expected the crawl or json output")
+
+// Crawl is the saved format of a crawl, used to compare crawls
+type Crawl struct {
+	Version int      `json:"version"`
+	Seed    string   `json:"seed"`
+	Pages   []Record `json:"pages"`
+}
+
+// Record is a saved crawled page
+type Record struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+	// status code, zero if the page failed or the crawl was saved without responses
+	Status int `json:"status,omitempty"`
+	// url of the response and the redirects followed to reach it
+	FinalURL  string   `json:"final_url,omitempty"`
+	Redirects []string `json:"redirects,omitempty"`
+	Title     string   `json:"title,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	// estimated importance of the page, zero if the crawl was saved without it
+	Importance float64 `json:"importance,omitempty"`
+	// links to other pages and the number of times they are linked
+	Links map[string]int `json:"links"`
+}
+
+// NewCrawl prepares the crawled pages to be saved with their estimated importance
+func NewCrawl(seed string, pages []crawler.TaskResult, importance map[string]float64) *Crawl {
+	c := Crawl{Version: CrawlVersion, Seed: seed, Pages: []Record{}}
+	for _, p := range pages {
+		r := NewRecord(p)
+		r.Importance = importance[r.URL]
+		c.Pages = append(c.Pages, r)
+	}
+	return &c
+}
+
+// NewRecord prepares a crawled page to be saved
+func NewRecord(p crawler.TaskResult) Record {
+	r := Record{URL: p.URL.String(), Depth: p.Depth, Title: p.Title, Links: p.Children}
+	if r.Links == nil {
+		r.Links = make(map[string]int)
+	}
+	if p.Error != nil && *p.Error != nil {
+		r.Error = (*p.Error).Error()
+	}
+	if p.Response != nil {
+		r.Status = p.Response.StatusCode
+		if p.Response.URL != nil && p.Response.URL.String() != r.URL {
+			r.FinalURL = p.Response.URL.String()
+		}
+		for _, redirect := range p.Response.Redirects {
+			r.Redirects = append(r.Redirects, redirect.URL.String())
+		}
+	}
+	return r
+}
+
+// WriteCrawl writes the crawled pages in the saved crawl format
+func WriteCrawl(w io.Writer, seed string, pages []crawler.TaskResult, importance map[string]float64) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewCrawl(seed, pages, importance))
+}
+
+// ReadCrawl reads a saved crawl, the json output with the links of each page is also accepted
+func ReadCrawl(r io.Reader) (*Crawl, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, ErrInvalidCrawl
+	}
+
+	// saved crawl format
+	if _, found := fields["version"]; found {
+		var c Crawl
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, ErrInvalidCrawl
+		}
+		return &c, nil
+	}
+
+	// json output, a map of the pages and their links
+	var hits map[string]map[string]int
+	if err := json.Unmarshal(data, &hits); err != nil {
+		return nil, ErrInvalidCrawl
+	}
+	c := Crawl{Pages: []Record{}}
+	for u, links := range hits {
+		if links == nil {
+			links = make(map[string]int)
+		}
+		c.Pages = append(c.Pages, Record{URL: u, Links: links})
+	}
+	sort.Slice(c.Pages, func(i, j int) bool {
+		return c.Pages[i].URL < c.Pages[j].URL
+	})
+	return &c, nil
+}
@@ -0,0 +1,24 @@
+package export_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/export"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrawl(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, export.WriteCrawl(&buf, "http://google.com", newCheckPages(), map[string]float64{"http://google.com": 0.5}))
+
+	c, err := export.ReadCrawl(&buf)
+	require.Nil(t, err)
+	require.Equal(t, export.CrawlVersion, c.Version)
+	require.Equal(t, "http://google.com", c.Seed)
+	require.Len(t, c.Pages, 4)
+	require.Equal(t, export.Record{URL: "http://google.com/broken", Depth: 1, Error: "connection refused", Links: map[string]int{}}, c.Pages[1])
+	require.Equal(t, []string{"http://google.com/a", "http://google.com/b"}, c.Pages[3].Redirects)
+	require.Equal(t, 404, c.Pages[3].Status)
+	require.Equal(t, 0.5, c.Pages[0].Importance)
+}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// slowestPages is the number of pages listed as the slowest
+const slowestPages = 20
+
+// htmlReport is the data rendered in the html report
+type htmlReport struct {
+	Seed      string
+	Generated string
+	Totals    struct {
+		Pages    int
+		Failed   int
+		Links    int
+		Bytes    int
+		Broken   int
+		Redirect int
+		AvgTime  string
+	}
+	Statuses  []htmlCount
+	Depths    []htmlCount
+	Slowest   []htmlPage
+	Broken    []htmlBroken
+	Redirects []htmlRedirect
+	Failed    []htmlPage
+	Pages     []htmlPage
+}
+
+// htmlCount is a bar of a distribution
+type htmlCount struct {
+	Label   string
+	Count   int
+	Percent float64
+}
+
+// htmlPage is a row of the page tables
+type htmlPage struct {
+	URL    string
+	Depth  int
+	Status int
+	Type   string
+	Bytes  int
+	TimeMs float64
+	Title  string
+	Tries  int
+	Error  string
+}
+
+// htmlBroken is a broken link and the pages linking to it
+type htmlBroken struct {
+	URL       string
+	Status    string
+	Referrers []string
+}
+
+// htmlRedirect is a redirect chain
+type htmlRedirect struct {
+	Hops  []crawler.Redirect
+	Final string
+}
+
+// WriteHTML writes a self-contained html report of the crawl
+func WriteHTML(w io.Writer, seed string, pages []crawler.TaskResult) error {
+	return htmlTemplate.Execute(w, newHTMLReport(seed, pages))
+}
+
+// newHTMLReport summarizes the crawled pages
+func newHTMLReport(seed string, pages []crawler.TaskResult) *htmlReport {
+	r := htmlReport{Seed: seed, Generated: time.Now().UTC().Format(time.RFC1123)}
+
+	var statuses = make(map[int]int)
+	var depths = make(map[int]int)
+	var broken = make(map[string]*htmlBroken)
+	var duration time.Duration
+	var fetched int
+	for _, p := range pages {
+		row := htmlPage{URL: p.URL.String(), Depth: p.Depth, Title: p.Title, Tries: p.Tries}
+		if p.Error != nil && *p.Error != nil {
+			row.Error = (*p.Error).Error()
+		}
+		if p.Response != nil {
+			row.Status = p.Response.StatusCode
+			row.Type = p.Response.Header.Get("Content-Type")
+			row.Bytes = p.Response.Size
+			row.TimeMs = float64(p.Response.Duration) / float64(time.Millisecond)
+			duration += p.Response.Duration
+			fetched++
+			r.Totals.Bytes += p.Response.Size
+			statuses[p.Response.StatusCode]++
+			if len(p.Response.Redirects) != 0 {
+				r.Redirects = append(r.Redirects, htmlRedirect{Hops: p.Response.Redirects, Final: p.Response.URL.String()})
+			}
+		}
+		depths[p.Depth]++
+		r.Totals.Links += len(p.Children)
+		r.Pages = append(r.Pages, row)
+
+		if row.Error != "" {
+			r.Failed = append(r.Failed, row)
+		}
+		if row.Error != "" && p.Response == nil {
+			broken[row.URL] = &htmlBroken{URL: row.URL, Status: row.Error}
+		} else if row.Status >= 400 {
+			broken[row.URL] = &htmlBroken{URL: row.URL, Status: strconv.Itoa(row.Status)}
+		}
+	}
+
+	// find the pages linking to the broken links
+	for _, p := range pages {
+		for c := range p.Children {
+			if parsed, err := url.Parse(c); err == nil {
+				c = parsed.String()
+			}
+			if b, found := broken[c]; found {
+				b.Referrers = append(b.Referrers, p.URL.String())
+			}
+		}
+	}
+	for _, b := range broken {
+		sort.Strings(b.Referrers)
+		r.Broken = append(r.Broken, *b)
+	}
+	sort.Slice(r.Broken, func(i, j int) bool {
+		return r.Broken[i].URL < r.Broken[j].URL
+	})
+
+	r.Totals.Pages = len(pages)
+	r.Totals.Failed = len(r.Failed)
+	r.Totals.Broken = len(r.Broken)
+	r.Totals.Redirect = len(r.Redirects)
+	if fetched != 0 {
+		r.Totals.AvgTime = (duration / time.Duration(fetched)).Round(time.Millisecond).String()
+	}
+	r.Statuses = distribution(statuses, fetched)
+	r.Depths = distribution(depths, len(pages))
+
+	r.Slowest = append(r.Slowest, r.Pages...)
+	sort.SliceStable(r.Slowest, func(i, j int) bool {
+		return r.Slowest[i].TimeMs > r.Slowest[j].TimeMs
+	})
+	if len(r.Slowest) > slowestPages {
+		r.Slowest = r.Slowest[:slowestPages]
+	}
+	return &r
+}
+
+// distribution returns the bars of a distribution sorted by key
+func distribution(counts map[int]int, total int) []htmlCount {
+	var keys []int
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	var result []htmlCount
+	for _, k := range keys {
+		c := htmlCount{Label: strconv.Itoa(k), Count: counts[k]}
+		if total != 0 {
+			c.Percent = 100 * float64(counts[k]) / float64(total)
+		}
+		result = append(result, c)
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package export
+
+import "html/template"
+
+// htmlTemplate renders the html report, styles and scripts are inlined so the report has no external assets
+var htmlTemplate = template.Must(template.New("report").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Crawl report {{.Seed}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; } h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; width: 100%; font-size: 0.9em; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; vertical-align: top; word-break: break-all; }
+th { background: #f4f4f4; }
+table.sortable th { cursor: pointer; user-select: none; }
+.totals { display: flex; flex-wrap: wrap; gap: 1em; }
+.total { background: #f4f4f4; padding: 0.8em 1.2em; border-radius: 4px; }
+.total b { display: block; font-size: 1.4em; }
+.bar { background: #4a7bd0; height: 1em; }
+.muted { color: #777; }
+input.search { width: 100%; padding: 6px; margin-bottom: 0.5em; box-sizing: border-box; }
+</style>
+</head>
+<body>
+<h1>Crawl report {{.Seed}}</h1>
+<p class="muted">Generated {{.Generated}}</p>
+
+<div class="totals">
+<div class="total"><b>{{.Totals.Pages}}</b>pages</div>
+<div class="total"><b>{{.Totals.Failed}}</b>failed</div>
+<div class="total"><b>{{.Totals.Broken}}</b>broken links</div>
+<div class="total"><b>{{.Totals.Redirect}}</b>redirects</div>
+<div class="total"><b>{{.Totals.Links}}</b>links</div>
+<div class="total"><b>{{.Totals.Bytes}}</b>bytes</div>
+<div class="total"><b>{{or .Totals.AvgTime "-"}}</b>average response time</div>
+</div>
+
+<h2>Status codes</h2>
+<table>
+<tr><th>Status</th><th>Pages</th><th style="width:50%"></th></tr>
+{{range .Statuses}}<tr><td>{{.Label}}</td><td>{{.Count}}</td><td><div class="bar" style="width: {{printf "%.1f" .Percent}}%"></div></td></tr>
+{{end}}</table>
+
+<h2>Depth</h2>
+<table>
+<tr><th>Depth</th><th>Pages</th><th style="width:50%"></th></tr>
+{{range .Depths}}<tr><td>{{.Label}}</td><td>{{.Count}}</td><td><div class="bar" style="width: {{printf "%.1f" .Percent}}%"></div></td></tr>
+{{end}}</table>
+
+<h2>Slowest pages</h2>
+<table class="sortable">
+<tr><th>URL</th><th>Status</th><th>Time (ms)</th><th>Bytes</th></tr>
+{{range .Slowest}}<tr><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Status}}</td><td>{{printf "%.1f" .TimeMs}}</td><td>{{.Bytes}}</td></tr>
+{{end}}</table>
+
+<h2>Broken links</h2>
+{{if .Broken}}<table class="sortable">
+<tr><th>URL</th><th>Status</th><th>Linked from</th></tr>
+{{range .Broken}}<tr><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Status}}</td><td>{{range .Referrers}}<a href="{{.}}">{{.}}</a><br>{{else}}<span class="muted">seed</span>{{end}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">No broken links.</p>{{end}}
+
+<h2>Redirect chains</h2>
+{{if .Redirects}}<table>
+<tr><th>Chain</th><th>Final URL</th></tr>
+{{range .Redirects}}<tr><td>{{range .Hops}}{{.URL}} <span class="muted">({{.StatusCode}})</span><br>{{end}}</td><td><a href="{{.Final}}">{{.Final}}</a></td></tr>
+{{end}}</table>{{else}}<p class="muted">No redirects.</p>{{end}}
+
+<h2>Failed tasks</h2>
+{{if .Failed}}<table class="sortable">
+<tr><th>URL</th><th>Tries</th><th>Error</th></tr>
+{{range .Failed}}<tr><td>{{.URL}}</td><td>{{.Tries}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">No failed tasks.</p>{{end}}
+
+<h2>All pages</h2>
+<input class="search" type="search" placeholder="Search pages" data-table="pages">
+<table class="sortable" id="pages">
+<tr><th>URL</th><th>Depth</th><th>Status</th><th>Type</th><th>Bytes</th><th>Time (ms)</th><th>Title</th></tr>
+{{range .Pages}}<tr><td><a href="{{.URL}}">{{.URL}}</a></td><td>{{.Depth}}</td><td>{{.Status}}</td><td>{{.Type}}</td><td>{{.Bytes}}</td><td>{{printf "%.1f" .TimeMs}}</td><td>{{.Title}}</td></tr>
+{{end}}</table>
+
+<script>
+// filter the rows of a table with the search box
+document.querySelectorAll("input.search").forEach(function (input) {
+  input.addEventListener("input", function () {
+    var query = input.value.toLowerCase();
+    var rows = document.getElementById(input.dataset.table).rows;
+    for (var i = 1; i < rows.length; i++) {
+      rows[i].style.display = rows[i].textContent.toLowerCase().indexOf(query) === -1 ? "none" : "";
+    }
+  });
+});
+// sort the rows of a table by the clicked column, numbers are sorted numerically
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table");
+    var column = th.cellIndex;
+    var asc = th.dataset.order !== "asc";
+    th.dataset.order = asc ? "asc" : "desc";
+    var rows = Array.prototype.slice.call(table.rows, 1);
+    rows.sort(function (a, b) {
+      var x = a.cells[column].textContent, y = b.cells[column].textContent;
+      var n = parseFloat(x) - parseFloat(y);
+      var c = isNaN(n) ? x.localeCompare(y) : n;
+      return asc ? c : -c;
+    });
+    rows.forEach(function (row) { table.tBodies[0].appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
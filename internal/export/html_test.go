@@ -0,0 +1,40 @@
+package export_test
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/export"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTML(t *testing.T) {
+	pages := newPages()
+	old, _ := url.Parse("http://google.com/old")
+	final, _ := url.Parse("http://google.com/new")
+	pages = append(pages, crawler.TaskResult{
+		Task:     crawler.Task{URL: old, Depth: 1, Tries: 1},
+		Response: &crawler.Response{URL: final, StatusCode: 200, Redirects: []crawler.Redirect{{URL: old, StatusCode: 301}}},
+		Title:    "<script>alert(1)</script>",
+	})
+
+	var buf bytes.Buffer
+	require.Nil(t, export.WriteHTML(&buf, "http://google.com", pages))
+	report := buf.String()
+
+	// totals and distributions
+	require.Contains(t, report, "<b>3</b>pages")
+	require.Contains(t, report, "<b>1</b>failed")
+	require.Contains(t, report, `<tr><td>200</td><td>2</td><td><div class="bar" style="width: 100.0%"></div></td></tr>`)
+
+	// broken links with their referrers
+	require.Contains(t, report, `<td><a href="http://google.com/broken">http://google.com/broken</a></td><td>connection refused</td><td><a href="http://google.com">http://google.com</a><br></td>`)
+	// redirect chains
+	require.Contains(t, report, `http://google.com/old <span class="muted">(301)</span>`)
+	// values are escaped and there are no external assets
+	require.Contains(t, report, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	require.NotContains(t, report, "<script src")
+	require.NotContains(t, report, "<link")
+}
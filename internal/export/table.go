@@ -0,0 +1,145 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/fsutil"
+)
+
+// tabular formats
+const (
+	CSV = "csv"
+	TSV = "tsv"
+)
+
+// link kinds
+const (
+	LinkInternal = "internal"
+	LinkExternal = "external"
+)
+
+// ErrUnknownFormat is returned when the format is not known
+var ErrUnknownFormat = errors.New("unknown format, expected csv or tsv")
+
+// headers of the tables, new columns are only appended so the tables stay compatible
+var (
+	pagesHeader = []string{"url", "depth", "status", "content_type", "bytes", "response_time_ms", "title", "tries", "error"}
+	linksHeader = []string{"source", "target", "count", "kind", "anchor_text"}
+)
+
+// Pages returns the Processed and Failed pages sorted by url
+func Pages(processed, failed map[string]crawler.TaskResult) []crawler.TaskResult {
+	var pages []crawler.TaskResult
+	for _, p := range processed {
+		pages = append(pages, p)
+	}
+	for _, p := range failed {
+		pages = append(pages, p)
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].URL.String() < pages[j].URL.String()
+	})
+	return pages
+}
+
+// WriteTables writes the pages.csv and links.csv files, or .tsv, to a directory
+func WriteTables(dir, format string, pages []crawler.TaskResult) error {
+	comma, err := separator(format)
+	if err != nil {
+		return err
+	}
+	err = fsutil.WriteFile(filepath.Join(dir, "pages."+format), func(w io.Writer) error {
+		return WritePages(w, comma, pages)
+	})
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(filepath.Join(dir, "links."+format), func(w io.Writer) error {
+		return WriteLinks(w, comma, pages)
+	})
+}
+
+// WritePages writes a table with a row for each page
+func WritePages(w io.Writer, comma rune, pages []crawler.TaskResult) error {
+	t := csv.NewWriter(w)
+	t.Comma = comma
+	if err := t.Write(pagesHeader); err != nil {
+		return err
+	}
+	for _, p := range pages {
+		var status, contentType, size, duration, failure string
+		if p.Response != nil {
+			status = strconv.Itoa(p.Response.StatusCode)
+			contentType = p.Response.Header.Get("Content-Type")
+			size = strconv.Itoa(p.Response.Size)
+			duration = strconv.FormatFloat(p.Response.Duration.Seconds()*1000, 'f', 3, 64)
+		}
+		if p.Error != nil && *p.Error != nil {
+			failure = (*p.Error).Error()
+		}
+		row := []string{p.URL.String(), strconv.Itoa(p.Depth), status, contentType, size, duration, p.Title, strconv.Itoa(p.Tries), failure}
+		if err := t.Write(neutralize(row)); err != nil {
+			return err
+		}
+	}
+	t.Flush()
+	return t.Error()
+}
+
+// WriteLinks writes a table with a row for each link between pages
+func WriteLinks(w io.Writer, comma rune, pages []crawler.TaskResult) error {
+	t := csv.NewWriter(w)
+	t.Comma = comma
+	if err := t.Write(linksHeader); err != nil {
+		return err
+	}
+	for _, p := range pages {
+		var targets []string
+		for c := range p.Children {
+			targets = append(targets, c)
+		}
+		sort.Strings(targets)
+		for _, target := range targets {
+			kind := LinkExternal
+			if u, err := url.Parse(target); err == nil && u.Host == p.URL.Host {
+				kind = LinkInternal
+			}
+			row := []string{p.URL.String(), target, strconv.Itoa(p.Children[target]), kind, strings.Join(p.Anchors[target], " | ")}
+			if err := t.Write(neutralize(row)); err != nil {
+				return err
+			}
+		}
+	}
+	t.Flush()
+	return t.Error()
+}
+
+// neutralize prefixes the cells starting like a formula with a quote, so spreadsheets show page titles and links as text
+func neutralize(row []string) []string {
+	for i, cell := range row {
+		if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+			row[i] = "'" + cell
+		}
+	}
+	return row
+}
+
+// separator returns the field separator of a format
+func separator(format string) (rune, error) {
+	switch format {
+	case CSV:
+		return ',', nil
+	case TSV:
+		return '\t', nil
+	default:
+		return 0, ErrUnknownFormat
+	}
+}
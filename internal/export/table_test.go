@@ -0,0 +1,82 @@
+package export_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/export"
+	"github.com/stretchr/testify/require"
+)
+
+// newPages returns a Processed and a Failed page
+func newPages() []crawler.TaskResult {
+	root, _ := url.Parse("http://google.com")
+	failed, _ := url.Parse("http://google.com/broken")
+	err := errors.New("connection refused")
+	return export.Pages(map[string]crawler.TaskResult{
+		root.String(): {
+			Task:     crawler.Task{URL: root, Depth: 0, Tries: 1},
+			Children: map[string]int{"http://google.com/broken": 2, "http://docs.google.com": 1},
+			Response: &crawler.Response{StatusCode: 200, Header: http.Header{"Content-Type": {"text/html"}}, Size: 120, Duration: 1500 * time.Microsecond},
+			Title:    `Google, "Search"`,
+			Anchors:  map[string][]string{"http://google.com/broken": {"Broken", "Again"}},
+		},
+	}, map[string]crawler.TaskResult{
+		failed.String(): {Task: crawler.Task{URL: failed, Depth: 1, Tries: 4}, Error: &err},
+	})
+}
+
+func TestTables(t *testing.T) {
+	var buf bytes.Buffer
+	require.Nil(t, export.WritePages(&buf, ',', newPages()))
+	require.Equal(t, `url,depth,status,content_type,bytes,response_time_ms,title,tries,error
+http://google.com,0,200,text/html,120,1.500,"Google, ""Search""",1,
+http://google.com/broken,1,,,,,,4,connection refused
+`, buf.String())
+
+	buf.Reset()
+	require.Nil(t, export.WriteLinks(&buf, '\t', newPages()))
+	require.Equal(t, "source\ttarget\tcount\tkind\tanchor_text\n"+
+		"http://google.com\thttp://docs.google.com\t1\texternal\t\n"+
+		"http://google.com\thttp://google.com/broken\t2\tinternal\tBroken | Again\n", buf.String())
+}
+
+func TestTables_formulas(t *testing.T) {
+	root, _ := url.Parse("http://google.com")
+	pages := []crawler.TaskResult{{
+		Task:     crawler.Task{URL: root, Tries: 1},
+		Children: map[string]int{"http://google.com/a": 1},
+		Title:    `=HYPERLINK("http://evil.com","click")`,
+		Anchors:  map[string][]string{"http://google.com/a": {"@SUM(1+1)"}},
+	}}
+
+	// the cells starting like a formula are quoted
+	var buf bytes.Buffer
+	require.Nil(t, export.WritePages(&buf, ',', pages))
+	require.Contains(t, buf.String(), `"'=HYPERLINK(""http://evil.com"",""click"")"`)
+
+	buf.Reset()
+	require.Nil(t, export.WriteLinks(&buf, '\t', pages))
+	require.Contains(t, buf.String(), "\t'@SUM(1+1)\n")
+}
+
+func TestTables_files(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, export.WriteTables(dir, export.CSV, newPages()))
+	for _, f := range []string{"pages.csv", "links.csv"} {
+		_, err := os.Stat(filepath.Join(dir, f))
+		require.Nil(t, err)
+	}
+	require.Equal(t, export.ErrUnknownFormat, export.WriteTables(dir, "xlsx", newPages()))
+}
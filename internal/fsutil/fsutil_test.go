@@ -0,0 +1,64 @@
+package fsutil_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/fsutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsutil")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "page")
+
+	// concurrent writers of the same file do not clash
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			require.Nil(t, fsutil.WriteFile(name, func(w io.Writer) error {
+				_, err := io.WriteString(w, strconv.Itoa(i))
+				return err
+			}))
+		}(i)
+	}
+	wg.Wait()
+	data, err := ioutil.ReadFile(name)
+	require.Nil(t, err)
+	require.Len(t, data, 1)
+
+	// no temporary files are left behind
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+
+	// a failed write leaves the previous file untouched
+	err = fsutil.WriteFile(name, func(w io.Writer) error {
+		_, _ = io.WriteString(w, "partial")
+		return errors.New("write failed")
+	})
+	require.NotNil(t, err)
+	updated, err := ioutil.ReadFile(name)
+	require.Nil(t, err)
+	require.Equal(t, data, updated)
+	files, err = ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+
+	// the directory is created
+	require.Nil(t, fsutil.WriteFile(filepath.Join(dir, "reports", "page"), func(w io.Writer) error {
+		return nil
+	}))
+	_, err = os.Stat(filepath.Join(dir, "reports", "page"))
+	require.Nil(t, err)
+}
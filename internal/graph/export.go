@@ -0,0 +1,159 @@
+package graph
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// ErrInvalidCollapse is returned when the collapse mode is not known
+var ErrInvalidCollapse = errors.New("invalid collapse, expected host, path or path:N")
+
+// Collapse returns the key of the node a page is collapsed into
+type Collapse func(u *url.URL) string
+
+// CollapseHost collapses the pages of each host into a single node
+func CollapseHost(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// CollapsePath collapses the pages sharing the first n segments of their path into a single node
+func CollapsePath(n int) Collapse {
+	return func(u *url.URL) string {
+		var segments []string
+		for _, s := range strings.Split(u.EscapedPath(), "/") {
+			if s == "" {
+				continue
+			}
+			if len(segments) == n {
+				break
+			}
+			segments = append(segments, s)
+		}
+		return u.Scheme + "://" + u.Host + "/" + strings.Join(segments, "/")
+	}
+}
+
+// ParseCollapse parses a collapse mode such as host, path (one segment) or path:2
+func ParseCollapse(s string) (Collapse, error) {
+	switch {
+	case s == "host":
+		return CollapseHost, nil
+	case s == "path":
+		return CollapsePath(1), nil
+	case strings.HasPrefix(s, "path:"):
+		n, err := strconv.Atoi(strings.TrimPrefix(s, "path:"))
+		if err != nil || n < 0 {
+			return nil, ErrInvalidCollapse
+		}
+		return CollapsePath(n), nil
+	default:
+		return nil, ErrInvalidCollapse
+	}
+}
+
+// Node is a crawled page, or a group of collapsed pages, in the exported graph
+type Node struct {
+	ID string
+	// url of the page or the key of the collapsed pages
+	URL string
+	// depth, status and title of the page, or of the shallowest of the collapsed pages
+	Depth  int
+	Status int
+	Title  string
+	// number of pages in the node
+	Pages int
+}
+
+// Edge is the links between two nodes in the exported graph
+type Edge struct {
+	ID     string
+	Source string
+	Target string
+	// number of links
+	Weight int
+}
+
+// Export is the link graph of the crawled pages prepared to be exported
+// links to pages that were not crawled and links from a node to itself are ignored
+type Export struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+// NewExport prepares the link graph of the crawled pages to be exported, a nil collapse keeps every page in its own node
+func NewExport(pages map[string]crawler.TaskResult, collapse Collapse) *Export {
+	// group the pages into nodes
+	var keys = make(map[string]string)
+	var groups = make(map[string][]crawler.TaskResult)
+	for u, p := range pages {
+		key := u
+		if collapse != nil {
+			key = collapse(p.URL)
+		}
+		keys[u] = key
+		groups[key] = append(groups[key], p)
+	}
+	var sorted []string
+	for key := range groups {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var e Export
+	var ids = make(map[string]string)
+	for i, key := range sorted {
+		group := groups[key]
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].Depth != group[j].Depth {
+				return group[i].Depth < group[j].Depth
+			}
+			return group[i].URL.String() < group[j].URL.String()
+		})
+		n := Node{ID: "n" + strconv.Itoa(i), URL: key, Depth: group[0].Depth, Title: group[0].Title, Pages: len(group)}
+		if group[0].Response != nil {
+			n.Status = group[0].Response.StatusCode
+		}
+		ids[key] = n.ID
+		e.Nodes = append(e.Nodes, n)
+	}
+
+	// sum the links between the nodes
+	var weights = make(map[[2]string]int)
+	for u, p := range pages {
+		for c, count := range p.Children {
+			if parsed, err := url.Parse(c); err == nil {
+				c = parsed.String()
+			}
+			target, found := keys[c]
+			if !found || target == keys[u] {
+				continue
+			}
+			weights[[2]string{ids[keys[u]], ids[target]}] += count
+		}
+	}
+	for link, w := range weights {
+		e.Edges = append(e.Edges, Edge{Source: link[0], Target: link[1], Weight: w})
+	}
+	sort.Slice(e.Edges, func(i, j int) bool {
+		a, b := e.Edges[i], e.Edges[j]
+		if a.Source != b.Source {
+			return nodeIndex(a.Source) < nodeIndex(b.Source)
+		}
+		return nodeIndex(a.Target) < nodeIndex(b.Target)
+	})
+	for i := range e.Edges {
+		e.Edges[i].ID = "e" + strconv.Itoa(i)
+	}
+	return &e
+}
+
+// nodeIndex returns the index of a node from its id
+func nodeIndex(id string) int {
+	i, _ := strconv.Atoi(strings.TrimPrefix(id, "n"))
+	return i
+}
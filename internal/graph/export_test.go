@@ -0,0 +1,93 @@
+package graph_test
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/graph"
+	"github.com/stretchr/testify/require"
+)
+
+// newExportPages builds crawled pages with a title and status
+func newExportPages() map[string]crawler.TaskResult {
+	pages := newPages(map[string][]string{
+		"http://google.com":          {"http://google.com/docs/a", "http://google.com/docs/a", "http://google.com/docs/b"},
+		"http://google.com/docs/a":   {"http://google.com", "http://google.com/docs/b"},
+		"http://google.com/docs/b":   {},
+		"http://docs.google.com/a&b": {"http://google.com"},
+	})
+	for u, p := range pages {
+		p.Depth = len(p.URL.Path)
+		p.Title = "Page " + p.URL.Path
+		p.Response = &crawler.Response{StatusCode: 200}
+		pages[u] = p
+	}
+	return pages
+}
+
+func TestExport(t *testing.T) {
+	e := graph.NewExport(newExportPages(), nil)
+	require.Len(t, e.Nodes, 4)
+	require.Equal(t, graph.Node{ID: "n1", URL: "http://google.com", Depth: 0, Status: 200, Title: "Page ", Pages: 1}, e.Nodes[1])
+	require.Equal(t, []graph.Edge{
+		{ID: "e0", Source: "n0", Target: "n1", Weight: 1},
+		{ID: "e1", Source: "n1", Target: "n2", Weight: 2},
+		{ID: "e2", Source: "n1", Target: "n3", Weight: 1},
+		{ID: "e3", Source: "n2", Target: "n1", Weight: 1},
+		{ID: "e4", Source: "n2", Target: "n3", Weight: 1},
+	}, e.Edges)
+}
+
+func TestExport_collapse(t *testing.T) {
+	collapse, err := graph.ParseCollapse("path:1")
+	require.Nil(t, err)
+	e := graph.NewExport(newExportPages(), collapse)
+	require.Equal(t, []graph.Node{
+		{ID: "n0", URL: "http://docs.google.com/a&b", Depth: 4, Status: 200, Title: "Page /a&b", Pages: 1},
+		{ID: "n1", URL: "http://google.com/", Depth: 0, Status: 200, Title: "Page ", Pages: 1},
+		{ID: "n2", URL: "http://google.com/docs", Depth: 7, Status: 200, Title: "Page /docs/a", Pages: 2},
+	}, e.Nodes)
+	require.Equal(t, []graph.Edge{
+		{ID: "e0", Source: "n0", Target: "n1", Weight: 1},
+		{ID: "e1", Source: "n1", Target: "n2", Weight: 3},
+		{ID: "e2", Source: "n2", Target: "n1", Weight: 1},
+	}, e.Edges)
+
+	e = graph.NewExport(newExportPages(), graph.CollapseHost)
+	require.Len(t, e.Nodes, 2)
+	require.Equal(t, 3, e.Nodes[1].Pages)
+
+	_, err = graph.ParseCollapse("query")
+	require.Equal(t, graph.ErrInvalidCollapse, err)
+}
+
+func TestExport_formats(t *testing.T) {
+	e := graph.NewExport(newExportPages(), graph.CollapseHost)
+
+	var buf bytes.Buffer
+	require.Nil(t, e.WriteGraphML(&buf))
+	require.Contains(t, buf.String(), `<graph id="crawl" edgedefault="directed">`)
+	require.Contains(t, buf.String(), `<data key="url">http://docs.google.com</data>`)
+	require.Contains(t, buf.String(), `<edge id="e0" source="n0" target="n1">`)
+
+	buf.Reset()
+	require.Nil(t, e.WriteGEXF(&buf))
+	require.Contains(t, buf.String(), `<node id="n1" label="http://google.com">`)
+	require.Contains(t, buf.String(), `<edge id="e0" source="n0" target="n1" weight="1"></edge>`)
+
+	buf.Reset()
+	require.Nil(t, e.WriteDOT(&buf))
+	require.Equal(t, `digraph crawl {
+  n0 [label="http://docs.google.com", url="http://docs.google.com", depth=4, status=200, title="Page /a&b", pages=1];
+  n1 [label="http://google.com", url="http://google.com", depth=0, status=200, title="Page ", pages=3];
+  n0 -> n1 [weight=1];
+}
+`, buf.String())
+}
+
+func TestExport_collapseRoot(t *testing.T) {
+	u, _ := url.Parse("http://google.com")
+	require.Equal(t, "http://google.com/", graph.CollapsePath(2)(u))
+}
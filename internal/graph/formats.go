@@ -0,0 +1,186 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// graphML is the root element of a graphml document
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+// WriteGraphML writes the graph in the GraphML format
+func (e *Export) WriteGraphML(w io.Writer) error {
+	var doc graphML
+	doc.XMLNS = "http://graphml.graphdrawing.org/xmlns"
+	doc.Keys = []graphMLKey{
+		{ID: "url", For: "node", Name: "url", Type: "string"},
+		{ID: "depth", For: "node", Name: "depth", Type: "int"},
+		{ID: "status", For: "node", Name: "status", Type: "int"},
+		{ID: "title", For: "node", Name: "title", Type: "string"},
+		{ID: "pages", For: "node", Name: "pages", Type: "int"},
+		{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+	}
+	doc.Graph.ID = "crawl"
+	doc.Graph.EdgeDefault = "directed"
+	for _, n := range e.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n.ID, Data: []graphMLData{
+			{Key: "url", Value: n.URL},
+			{Key: "depth", Value: strconv.Itoa(n.Depth)},
+			{Key: "status", Value: strconv.Itoa(n.Status)},
+			{Key: "title", Value: n.Title},
+			{Key: "pages", Value: strconv.Itoa(n.Pages)},
+		}})
+	}
+	for _, edge := range e.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{ID: edge.ID, Source: edge.Source, Target: edge.Target, Data: []graphMLData{
+			{Key: "weight", Value: strconv.Itoa(edge.Weight)},
+		}})
+	}
+	return writeXML(w, doc)
+}
+
+// gexf is the root element of a gexf document
+type gexf struct {
+	XMLName xml.Name `xml:"gexf"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Graph   struct {
+		Mode            string `xml:"mode,attr"`
+		DefaultEdgeType string `xml:"defaultedgetype,attr"`
+		Attributes      struct {
+			Class      string          `xml:"class,attr"`
+			Attributes []gexfAttribute `xml:"attribute"`
+		} `xml:"attributes"`
+		Nodes []gexfNode `xml:"nodes>node"`
+		Edges []gexfEdge `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID     string      `xml:"id,attr"`
+	Label  string      `xml:"label,attr"`
+	Values []gexfValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+	Weight int    `xml:"weight,attr"`
+}
+
+// WriteGEXF writes the graph in the GEXF format
+func (e *Export) WriteGEXF(w io.Writer) error {
+	var doc gexf
+	doc.XMLNS = "http://www.gexf.net/1.2draft"
+	doc.Version = "1.2"
+	doc.Graph.Mode = "static"
+	doc.Graph.DefaultEdgeType = "directed"
+	doc.Graph.Attributes.Class = "node"
+	doc.Graph.Attributes.Attributes = []gexfAttribute{
+		{ID: "url", Title: "url", Type: "string"},
+		{ID: "depth", Title: "depth", Type: "integer"},
+		{ID: "status", Title: "status", Type: "integer"},
+		{ID: "title", Title: "title", Type: "string"},
+		{ID: "pages", Title: "pages", Type: "integer"},
+	}
+	for _, n := range e.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{ID: n.ID, Label: n.URL, Values: []gexfValue{
+			{For: "url", Value: n.URL},
+			{For: "depth", Value: strconv.Itoa(n.Depth)},
+			{For: "status", Value: strconv.Itoa(n.Status)},
+			{For: "title", Value: n.Title},
+			{For: "pages", Value: strconv.Itoa(n.Pages)},
+		}})
+	}
+	for _, edge := range e.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{ID: edge.ID, Source: edge.Source, Target: edge.Target, Weight: edge.Weight})
+	}
+	return writeXML(w, doc)
+}
+
+// WriteDOT writes the graph in the Graphviz DOT format
+func (e *Export) WriteDOT(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph crawl {")
+	for _, n := range e.Nodes {
+		fmt.Fprintf(b, "  %s [label=%s, url=%s, depth=%d, status=%d, title=%s, pages=%d];\n",
+			n.ID, quoteDOT(n.URL), quoteDOT(n.URL), n.Depth, n.Status, quoteDOT(n.Title), n.Pages)
+	}
+	for _, edge := range e.Edges {
+		fmt.Fprintf(b, "  %s -> %s [weight=%d];\n", edge.Source, edge.Target, edge.Weight)
+	}
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+// quoteDOT quotes a string as a DOT identifier
+func quoteDOT(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+// writeXML writes an indented xml document
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
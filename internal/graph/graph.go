@@ -0,0 +1,276 @@
+package graph
+
+import (
+	"net/url"
+	"sort"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// Graph is the directed graph of the links between the crawled pages
+// links to pages that were not crawled and links from a page to itself are ignored
+type Graph struct {
+	// crawled pages sorted by url
+	Nodes []string
+
+	index map[string]int
+	out   [][]int
+	in    [][]int
+}
+
+// New builds the link graph of the crawled pages
+func New(pages map[string]crawler.TaskResult) *Graph {
+	g := Graph{index: make(map[string]int)}
+	for u := range pages {
+		g.Nodes = append(g.Nodes, u)
+	}
+	sort.Strings(g.Nodes)
+	for i, u := range g.Nodes {
+		g.index[u] = i
+	}
+
+	g.out = make([][]int, len(g.Nodes))
+	g.in = make([][]int, len(g.Nodes))
+	for i, u := range g.Nodes {
+		var links []int
+		for c := range pages[u].Children {
+			if parsed, err := url.Parse(c); err == nil {
+				c = parsed.String()
+			}
+			j, found := g.index[c]
+			if !found || j == i {
+				continue
+			}
+			links = append(links, j)
+		}
+		sort.Ints(links)
+		g.out[i] = links
+		for _, j := range links {
+			g.in[j] = append(g.in[j], i)
+		}
+	}
+	return &g
+}
+
+// Links returns the number of links in the graph
+func (g *Graph) Links() int {
+	var n int
+	for _, links := range g.out {
+		n += len(links)
+	}
+	return n
+}
+
+// Outlinks returns the crawled pages linked from a page
+func (g *Graph) Outlinks(u string) []string {
+	i, found := g.index[u]
+	if !found {
+		return nil
+	}
+	return g.urls(g.out[i])
+}
+
+// Inlinks returns the crawled pages linking to a page
+func (g *Graph) Inlinks(u string) []string {
+	i, found := g.index[u]
+	if !found {
+		return nil
+	}
+	return g.urls(g.in[i])
+}
+
+// InDegree returns the number of crawled pages linking to a page
+func (g *Graph) InDegree(u string) int {
+	return len(g.Inlinks(u))
+}
+
+// OutDegree returns the number of crawled pages linked from a page
+func (g *Graph) OutDegree(u string) int {
+	return len(g.Outlinks(u))
+}
+
+// PageRank computes the PageRank of the pages with the damping factor, until the scores converge
+// the rank of the pages without links is distributed over all the pages, so the scores add up to 1
+func (g *Graph) PageRank(damping float64) map[string]float64 {
+	n := float64(len(g.Nodes))
+	rank := make([]float64, len(g.Nodes))
+	for i := range rank {
+		rank[i] = 1 / n
+	}
+	for iteration := 0; iteration < 100; iteration++ {
+		var dangling float64
+		for i, links := range g.out {
+			if len(links) == 0 {
+				dangling += rank[i]
+			}
+		}
+		next := make([]float64, len(g.Nodes))
+		for i := range next {
+			next[i] = (1-damping)/n + damping*dangling/n
+		}
+		for i, links := range g.out {
+			for _, j := range links {
+				next[j] += damping * rank[i] / float64(len(links))
+			}
+		}
+		var diff float64
+		for i := range rank {
+			if next[i] > rank[i] {
+				diff += next[i] - rank[i]
+			} else {
+				diff += rank[i] - next[i]
+			}
+		}
+		rank = next
+		if diff < 1e-9 {
+			break
+		}
+	}
+
+	var result = make(map[string]float64)
+	for i, u := range g.Nodes {
+		result[u] = rank[i]
+	}
+	return result
+}
+
+// Depths returns the click depth of the pages, the min number of links followed from the seed to reach them
+// pages not reachable from the seed are not included
+func (g *Graph) Depths(seed string) map[string]int {
+	var result = make(map[string]int)
+	i, found := g.index[seed]
+	if !found {
+		return result
+	}
+	depth := map[int]int{i: 0}
+	queue := []int{i}
+	for len(queue) > 0 {
+		i, queue = queue[0], queue[1:]
+		for _, j := range g.out[i] {
+			if _, found := depth[j]; !found {
+				depth[j] = depth[i] + 1
+				queue = append(queue, j)
+			}
+		}
+	}
+	for i, d := range depth {
+		result[g.Nodes[i]] = d
+	}
+	return result
+}
+
+// Components returns the strongly connected components of the graph, largest first
+func (g *Graph) Components() [][]string {
+	// tarjan's algorithm, following the links with an explicit stack so long chains of pages can't overflow it
+	type call struct {
+		// page being visited and the position of the next link to follow
+		node, link int
+	}
+	var (
+		index   = make([]int, len(g.Nodes))
+		low     = make([]int, len(g.Nodes))
+		onStack = make([]bool, len(g.Nodes))
+		stack   []int
+		calls   []call
+		next    = 1
+		result  [][]string
+	)
+	visit := func(i int) {
+		index[i], low[i] = next, next
+		next++
+		stack = append(stack, i)
+		onStack[i] = true
+		calls = append(calls, call{node: i})
+	}
+	for root := range g.Nodes {
+		if index[root] != 0 {
+			continue
+		}
+		visit(root)
+		for len(calls) != 0 {
+			c := &calls[len(calls)-1]
+			i := c.node
+			if c.link < len(g.out[i]) {
+				j := g.out[i][c.link]
+				c.link++
+				if index[j] == 0 {
+					visit(j)
+				} else if onStack[j] && index[j] < low[i] {
+					low[i] = index[j]
+				}
+				continue
+			}
+
+			// all the links of the page were followed, return to the page linking to it
+			calls = calls[:len(calls)-1]
+			if len(calls) != 0 {
+				if p := calls[len(calls)-1].node; low[i] < low[p] {
+					low[p] = low[i]
+				}
+			}
+			if low[i] != index[i] {
+				continue
+			}
+			var component []int
+			for {
+				j := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[j] = false
+				component = append(component, j)
+				if j == i {
+					break
+				}
+			}
+			sort.Ints(component)
+			result = append(result, g.urls(component))
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if len(result[i]) != len(result[j]) {
+			return len(result[i]) > len(result[j])
+		}
+		return result[i][0] < result[j][0]
+	})
+	return result
+}
+
+// DeadEnds returns the pages without links to other crawled pages
+func (g *Graph) DeadEnds() []string {
+	var result []string
+	for i, u := range g.Nodes {
+		if len(g.out[i]) == 0 {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// Orphans returns the pages, other than the seed, that no crawled page links to
+func (g *Graph) Orphans(seed string) []string {
+	return g.withInDegree(seed, 0)
+}
+
+// SingleLinked returns the pages, other than the seed, that are only linked from one crawled page
+func (g *Graph) SingleLinked(seed string) []string {
+	return g.withInDegree(seed, 1)
+}
+
+// withInDegree returns the pages, other than the seed, with an in degree
+func (g *Graph) withInDegree(seed string, n int) []string {
+	var result []string
+	for i, u := range g.Nodes {
+		if u != seed && len(g.in[i]) == n {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// urls returns the urls of the nodes
+func (g *Graph) urls(nodes []int) []string {
+	var result []string
+	for _, i := range nodes {
+		result = append(result, g.Nodes[i])
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package graph_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/graph"
+	"github.com/stretchr/testify/require"
+)
+
+// newPages builds the crawled pages from their links
+func newPages(links map[string][]string) map[string]crawler.TaskResult {
+	var pages = make(map[string]crawler.TaskResult)
+	for u, children := range links {
+		parsed, _ := url.Parse(u)
+		result := crawler.TaskResult{Task: crawler.Task{URL: parsed}, Children: make(map[string]int)}
+		for _, c := range children {
+			result.Children[c]++
+		}
+		pages[u] = result
+	}
+	return pages
+}
+
+func TestGraph(t *testing.T) {
+	// a <-> b -> c, d is an orphan and the external link is ignored
+	g := graph.New(newPages(map[string][]string{
+		"http://google.com/a": {"http://google.com/b", "http://google.com/a", "http://docs.google.com"},
+		"http://google.com/b": {"http://google.com/a", "http://google.com/c"},
+		"http://google.com/c": {},
+		"http://google.com/d": {"http://google.com/c"},
+	}))
+	seed := "http://google.com/a"
+
+	require.Equal(t, 4, g.Links())
+	require.Equal(t, 1, g.OutDegree(seed))
+	require.Equal(t, 2, g.InDegree("http://google.com/c"))
+	require.Equal(t, map[string]int{"http://google.com/a": 0, "http://google.com/b": 1, "http://google.com/c": 2}, g.Depths(seed))
+	require.Equal(t, [][]string{{"http://google.com/a", "http://google.com/b"}, {"http://google.com/c"}, {"http://google.com/d"}}, g.Components())
+	require.Equal(t, []string{"http://google.com/c"}, g.DeadEnds())
+	require.Equal(t, []string{"http://google.com/d"}, g.Orphans(seed))
+	require.Equal(t, []string{"http://google.com/b"}, g.SingleLinked(seed))
+
+	// the ranks add up to 1 and the dead end collects the most rank
+	rank := g.PageRank(graph.Damping)
+	var total float64
+	for _, r := range rank {
+		total += r
+	}
+	require.InDelta(t, 1, total, 0.0001)
+	require.True(t, rank["http://google.com/c"] > rank["http://google.com/b"])
+	require.True(t, rank["http://google.com/d"] < rank["http://google.com/b"])
+
+	// report
+	r := g.Analyze(seed)
+	require.Equal(t, "http://google.com/c", r.Pages[0].URL)
+	require.Equal(t, []string{"http://google.com/d"}, r.Unreachable)
+	require.Equal(t, 2, r.MaxDepth)
+	var buf bytes.Buffer
+	require.Nil(t, r.WriteSummary(&buf))
+	require.Contains(t, buf.String(), "pages: 4\nlinks: 4\n")
+}
+
+func TestGraph_components(t *testing.T) {
+	// a long chain of pages linking back to the first one is a single component, followed by a page outside of it
+	var links = make(map[string][]string)
+	for i := 0; i < 100000; i++ {
+		links[fmt.Sprintf("http://google.com/%06d", i)] = []string{fmt.Sprintf("http://google.com/%06d", i+1)}
+	}
+	links["http://google.com/100000"] = []string{"http://google.com/000000", "http://google.com/end"}
+	links["http://google.com/end"] = nil
+
+	components := graph.New(newPages(links)).Components()
+	require.Len(t, components, 2)
+	require.Len(t, components[0], 100001)
+	require.Equal(t, []string{"http://google.com/end"}, components[1])
+}
@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Damping is the PageRank damping factor used in the reports
+const Damping = 0.85
+
+// Page is the analysis of a crawled page
+type Page struct {
+	URL       string  `json:"url"`
+	PageRank  float64 `json:"pagerank"`
+	InDegree  int     `json:"in_degree"`
+	OutDegree int     `json:"out_degree"`
+	// click depth from the seed, -1 if the page is not reachable from the seed
+	Depth int `json:"depth"`
+}
+
+// Report is the analysis of the link graph
+type Report struct {
+	Seed  string `json:"seed"`
+	Links int    `json:"links"`
+	// pages sorted by PageRank
+	Pages        []Page     `json:"pages"`
+	Components   [][]string `json:"components"`
+	DeadEnds     []string   `json:"dead_ends"`
+	Orphans      []string   `json:"orphans"`
+	SingleLinked []string   `json:"single_linked"`
+	Unreachable  []string   `json:"unreachable"`
+	MaxDepth     int        `json:"max_depth"`
+}
+
+// Analyze analyzes the link graph from the seed
+func (g *Graph) Analyze(seed string) *Report {
+	r := Report{
+		Seed:         seed,
+		Links:        g.Links(),
+		Components:   g.Components(),
+		DeadEnds:     g.DeadEnds(),
+		Orphans:      g.Orphans(seed),
+		SingleLinked: g.SingleLinked(seed),
+	}
+	rank := g.PageRank(Damping)
+	depths := g.Depths(seed)
+	for i, u := range g.Nodes {
+		depth, found := depths[u]
+		if !found {
+			depth = -1
+			r.Unreachable = append(r.Unreachable, u)
+		}
+		if depth > r.MaxDepth {
+			r.MaxDepth = depth
+		}
+		r.Pages = append(r.Pages, Page{URL: u, PageRank: rank[u], InDegree: len(g.in[i]), OutDegree: len(g.out[i]), Depth: depth})
+	}
+	sort.SliceStable(r.Pages, func(i, j int) bool {
+		return r.Pages[i].PageRank > r.Pages[j].PageRank
+	})
+	return &r
+}
+
+// WriteSummary writes a human readable summary of the report
+func (r *Report) WriteSummary(w io.Writer) error {
+	var largest int
+	if len(r.Components) != 0 {
+		largest = len(r.Components[0])
+	}
+	lines := []string{
+		fmt.Sprintf("pages: %d", len(r.Pages)),
+		fmt.Sprintf("links: %d", r.Links),
+		fmt.Sprintf("max click depth: %d", r.MaxDepth),
+		fmt.Sprintf("strongly connected components: %d (largest %d pages)", len(r.Components), largest),
+		fmt.Sprintf("dead ends: %d", len(r.DeadEnds)),
+		fmt.Sprintf("orphans: %d", len(r.Orphans)),
+		fmt.Sprintf("single linked: %d", len(r.SingleLinked)),
+		fmt.Sprintf("unreachable from the seed: %d", len(r.Unreachable)),
+		"top pages by pagerank:",
+	}
+	for i, p := range r.Pages {
+		if i == 10 {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %.4f in=%d out=%d depth=%d %s", p.PageRank, p.InDegree, p.OutDegree, p.Depth, p.URL))
+	}
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/rs/zerolog"
+)
+
+// ErrUnsupportedURL is returned for urls that are neither file urls nor virtual hosts
+var ErrUnsupportedURL = errors.New("url is not a file or a virtual host")
+
+// Local is a backend that serves the pages from the local file system
+// it serves file:// urls and virtual hosts mapped onto a local directory
+type Local struct {
+	logger *zerolog.Logger
+
+	// directories served for each virtual host
+	hosts map[string]string
+	// files served for directory urls
+	indexFiles []string
+}
+
+// Option is an optimal configuration option that can be applied to a local backend
+type Option func(l *Local)
+
+// New instantiates a new local backend
+func New(logger *zerolog.Logger, opts ...Option) *Local {
+	l := logger.With().Str("pkg", "local").Logger()
+	b := Local{
+		logger:     &l,
+		hosts:      make(map[string]string),
+		indexFiles: []string{"index.html", "index.htm"},
+	}
+	for _, opt := range opts {
+		opt(&b)
+	}
+	return &b
+}
+
+// AddVirtualHost serves the http and https urls of a host from a directory
+func AddVirtualHost(host, dir string) Option {
+	return func(l *Local) {
+		l.hosts[strings.ToLower(host)] = dir
+	}
+}
+
+// SetIndexFiles sets the files served for directory urls
+func SetIndexFiles(names ...string) Option {
+	return func(l *Local) {
+		l.indexFiles = names
+	}
+}
+
+// Do returns the file of the url, missing files are returned as 404 responses
+func (l *Local) Do(ctx context.Context, u *url.URL) (*crawler.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	// find the file of the url
+	var name string
+	var listing bool
+	switch {
+	case u.Scheme == "file":
+		name = filepath.FromSlash(path.Clean("/" + u.Path))
+		listing = true
+	case u.Scheme == "http" || u.Scheme == "https":
+		dir, found := l.hosts[strings.ToLower(u.Host)]
+		if !found {
+			return nil, ErrUnsupportedURL
+		}
+		name = filepath.Join(dir, filepath.FromSlash(path.Clean("/"+u.Path)))
+	default:
+		return nil, ErrUnsupportedURL
+	}
+	l.logger.Debug().Str("url", u.String()).Str("file", name).Msg("reading file")
+
+	res := crawler.Response{
+		URL:        u,
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Time:       start,
+	}
+	info, err := os.Stat(name)
+	if err == nil && info.IsDir() {
+		// directories are served with a trailing slash so relative links are resolved inside them
+		if !strings.HasSuffix(u.Path, "/") {
+			dirURL := *u
+			dirURL.Path += "/"
+			res.URL = &dirURL
+		}
+		dir := name
+		name, info, err = l.index(dir)
+		if os.IsNotExist(err) && listing {
+			res.Body, err = directoryListing(dir, res.URL.Path)
+			if err != nil {
+				return nil, err
+			}
+			res.Header.Set("Content-Type", "text/html; charset=utf-8")
+			return l.done(&res), nil
+		}
+	}
+	if os.IsNotExist(err) {
+		res.StatusCode = http.StatusNotFound
+		return l.done(&res), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// read the file
+	res.Body, err = ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(res.Body)
+	}
+	res.Header.Set("Content-Type", contentType)
+	res.Header.Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	return l.done(&res), nil
+}
+
+// index returns the index file of a directory
+func (l *Local) index(dir string) (string, os.FileInfo, error) {
+	for _, n := range l.indexFiles {
+		name := filepath.Join(dir, n)
+		if info, err := os.Stat(name); err == nil && !info.IsDir() {
+			return name, info, nil
+		}
+	}
+	return "", nil, os.ErrNotExist
+}
+
+// done completes the response
+func (l *Local) done(res *crawler.Response) *crawler.Response {
+	res.Size = len(res.Body)
+	res.Header.Set("Content-Length", strconv.Itoa(res.Size))
+	res.Duration = time.Since(res.Time)
+	return res
+}
+
+// directoryListing returns a html page linking to all the entries of a directory
+func directoryListing(dir, base string) ([]byte, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("<html><body>\n")
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		href := (&url.URL{Path: base + name}).String()
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", html.EscapeString(href), html.EscapeString(name))
+	}
+	buf.WriteString("</body></html>\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package local_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/local"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	// generate a local build directory
+	dir, err := ioutil.TempDir("", "local")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "docs"), 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte(`<a href="/docs">docs</a>`), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "docs", "index.html"), []byte(`docs`), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "docs", "style.css"), []byte(`body{}`), 0644))
+
+	logger := zerolog.Nop()
+	b := local.New(&logger, local.AddVirtualHost("google.com", dir))
+
+	// virtual host
+	u, _ := url.Parse("https://google.com/")
+	res, err := b.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+	require.Equal(t, `<a href="/docs">docs</a>`, string(res.Body))
+
+	// directories are served with their index and a trailing slash
+	u, _ = url.Parse("https://google.com/docs")
+	res, err = b.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "https://google.com/docs/", res.URL.String())
+	require.Equal(t, "docs", string(res.Body))
+
+	// mime types are guessed from the extension
+	u, _ = url.Parse("https://google.com/docs/style.css")
+	res, err = b.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "text/css; charset=utf-8", res.Header.Get("Content-Type"))
+
+	// missing files are not found, including outside of the directory
+	for _, p := range []string{"https://google.com/missing", "https://google.com/../../etc/passwd"} {
+		u, _ = url.Parse(p)
+		res, err = b.Do(context.Background(), u)
+		require.Nil(t, err)
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+	}
+
+	// other hosts are not served
+	u, _ = url.Parse("https://docs.google.com/")
+	_, err = b.Do(context.Background(), u)
+	require.Equal(t, local.ErrUnsupportedURL, err)
+
+	// file urls
+	u = &url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "docs", "index.html"))}
+	res, err = b.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, "docs", string(res.Body))
+
+	// directories without an index are listed
+	require.Nil(t, os.Remove(filepath.Join(dir, "docs", "index.html")))
+	u = &url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "docs"))}
+	res, err = b.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Contains(t, string(res.Body), `<a href="`+u.Path+`/style.css">style.css</a>`)
+}
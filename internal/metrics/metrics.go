@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are the prometheus metrics of a crawl, recorded by the orchestrator, the workers and the backend
+type Metrics struct {
+	registry *prometheus.Registry
+
+	// orchestrator
+	frontier  prometheus.Gauge
+	inProcess prometheus.Gauge
+	processed prometheus.Counter
+	failed    prometheus.Counter
+	retries   prometheus.Counter
+
+	// workers
+	tasks        *prometheus.CounterVec
+	taskDuration prometheus.Histogram
+
+	// backend
+	requests         *prometheus.CounterVec
+	responses        *prometheus.CounterVec
+	responseDuration prometheus.Histogram
+	bytes            prometheus.Counter
+}
+
+// New instantiates the metrics in their own registry, along with the go runtime and process metrics
+func New() *Metrics {
+	m := Metrics{
+		registry: prometheus.NewRegistry(),
+		frontier: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "crawler_frontier_tasks",
+			Help: "Tasks waiting to be processed.",
+		}),
+		inProcess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "crawler_in_process_tasks",
+			Help: "Tasks being processed by the workers.",
+		}),
+		processed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_pages_processed_total",
+			Help: "Pages processed.",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_pages_failed_total",
+			Help: "Pages that failed to be processed after all the retries.",
+		}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_retries_total",
+			Help: "Failed tasks queued to be retried.",
+		}),
+		tasks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_worker_tasks_total",
+			Help: "Tasks processed by the workers, by result.",
+		}, []string{"result"}),
+		taskDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "crawler_worker_task_duration_seconds",
+			Help:    "Time taken by the workers to fetch and scrape a page.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_requests_total",
+			Help: "Requests sent, by host, the hosts over the first 100 are counted as other.",
+		}, []string{"host"}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crawler_responses_total",
+			Help: "Responses received, by status code, or error if no response was received.",
+		}, []string{"code"}),
+		responseDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "crawler_response_duration_seconds",
+			Help:    "Time taken to fetch a response.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		bytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "crawler_downloaded_bytes_total",
+			Help: "Bytes of the response bodies downloaded.",
+		}),
+	}
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.frontier, m.inProcess, m.processed, m.failed, m.retries,
+		m.tasks, m.taskDuration,
+		m.requests, m.responses, m.responseDuration, m.bytes,
+	)
+	return &m
+}
+
+// Handler returns the http handler exposing the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// SetQueued sets the number of tasks waiting to be processed
+func (m *Metrics) SetQueued(n int) {
+	m.frontier.Set(float64(n))
+}
+
+// SetInProcess sets the number of tasks being processed
+func (m *Metrics) SetInProcess(n int) {
+	m.inProcess.Set(float64(n))
+}
+
+// AddProcessed counts a processed page
+func (m *Metrics) AddProcessed() {
+	m.processed.Inc()
+}
+
+// AddFailed counts a page that failed after all the retries
+func (m *Metrics) AddFailed() {
+	m.failed.Inc()
+}
+
+// AddRetry counts a failed task queued to be retried
+func (m *Metrics) AddRetry() {
+	m.retries.Inc()
+}
+
+// ObserveTask records a task processed by a worker
+func (m *Metrics) ObserveTask(d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.tasks.WithLabelValues(result).Inc()
+	m.taskDuration.Observe(d.Seconds())
+}
+
+// maxHosts is the max number of host labels of the requests, the requests to the other hosts are counted as other
+const maxHosts = 100
+
+// Backend defines the backend used to fetch the pages
+type Backend interface {
+	DoWithHeader(ctx context.Context, u *url.URL, header http.Header) (*crawler.Response, error)
+}
+
+// InstrumentedBackend is a backend recording the requests and responses of another backend
+type InstrumentedBackend struct {
+	backend Backend
+	metrics *Metrics
+
+	// hosts with their own label
+	mu    sync.Mutex
+	hosts map[string]bool
+}
+
+// NewBackend instruments a backend, it should wrap the backend sending the requests rather than a cache
+func NewBackend(backend Backend, metrics *Metrics) *InstrumentedBackend {
+	return &InstrumentedBackend{backend: backend, metrics: metrics, hosts: make(map[string]bool)}
+}
+
+// host returns the host label of a request, other once there are too many hosts to keep the series bounded
+func (b *InstrumentedBackend) host(u *url.URL) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.hosts[u.Host] && len(b.hosts) >= maxHosts {
+		return "other"
+	}
+	b.hosts[u.Host] = true
+	return u.Host
+}
+
+// Do fetches the page and records the request, the status code, the response time and the bytes downloaded
+func (b *InstrumentedBackend) Do(ctx context.Context, u *url.URL) (*crawler.Response, error) {
+	return b.DoWithHeader(ctx, u, nil)
+}
+
+// DoWithHeader fetches the page adding the headers to the request, recording it like Do
+func (b *InstrumentedBackend) DoWithHeader(ctx context.Context, u *url.URL, header http.Header) (*crawler.Response, error) {
+	b.metrics.requests.WithLabelValues(b.host(u)).Inc()
+	start := time.Now()
+	res, err := b.backend.DoWithHeader(ctx, u, header)
+	if res == nil {
+		b.metrics.responses.WithLabelValues("error").Inc()
+		return res, err
+	}
+	b.metrics.responses.WithLabelValues(strconv.Itoa(res.StatusCode)).Inc()
+	duration := res.Duration
+	if duration == 0 {
+		duration = time.Since(start)
+	}
+	b.metrics.responseDuration.Observe(duration.Seconds())
+	b.metrics.bytes.Add(float64(res.Size))
+	return res, err
+}
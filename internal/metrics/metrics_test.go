@@ -0,0 +1,95 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+// backend returns a 404 for the missing page and an error for the broken page
+type backend struct{}
+
+func (backend) DoWithHeader(ctx context.Context, u *url.URL, header http.Header) (*crawler.Response, error) {
+	switch u.Path {
+	case "/missing":
+		return &crawler.Response{URL: u, StatusCode: http.StatusNotFound, Duration: time.Second}, nil
+	case "/broken":
+		return nil, errors.New("connection refused")
+	}
+	return &crawler.Response{URL: u, StatusCode: http.StatusOK, Size: 100, Duration: 100 * time.Millisecond}, nil
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	b := metrics.NewBackend(backend{}, m)
+	for _, p := range []string{"https://monzo.com/", "https://monzo.com/about", "https://monzo.com/missing", "https://google.com/broken"} {
+		u, _ := url.Parse(p)
+		_, _ = b.Do(context.Background(), u)
+	}
+	m.SetQueued(3)
+	m.SetInProcess(2)
+	m.AddProcessed()
+	m.AddRetry()
+	m.AddFailed()
+	m.ObserveTask(time.Second, nil)
+	m.ObserveTask(time.Second, errors.New("connection refused"))
+
+	// scrape the metrics
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+	res, err := http.Get(server.URL)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	require.Nil(t, err)
+	for _, line := range []string{
+		"crawler_frontier_tasks 3",
+		"crawler_in_process_tasks 2",
+		"crawler_pages_processed_total 1",
+		"crawler_pages_failed_total 1",
+		"crawler_retries_total 1",
+		`crawler_worker_tasks_total{result="error"} 1`,
+		`crawler_worker_tasks_total{result="ok"} 1`,
+		`crawler_requests_total{host="monzo.com"} 3`,
+		`crawler_requests_total{host="google.com"} 1`,
+		`crawler_responses_total{code="200"} 2`,
+		`crawler_responses_total{code="404"} 1`,
+		`crawler_responses_total{code="error"} 1`,
+		"crawler_response_duration_seconds_count 3",
+		"crawler_downloaded_bytes_total 200",
+	} {
+		require.True(t, strings.Contains(string(body), line+"\n"), line)
+	}
+}
+
+func TestMetrics_hosts(t *testing.T) {
+	m := metrics.New()
+	b := metrics.NewBackend(backend{}, m)
+	for i := 0; i < 150; i++ {
+		u, _ := url.Parse(fmt.Sprintf("https://%d.monzo.com/", i))
+		_, _ = b.Do(context.Background(), u)
+	}
+
+	// scrape the metrics
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+	res, err := http.Get(server.URL)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	require.Nil(t, err)
+	require.Equal(t, 101, strings.Count(string(body), "crawler_requests_total{"))
+	require.True(t, strings.Contains(string(body), `crawler_requests_total{host="99.monzo.com"} 1`+"\n"))
+	require.True(t, strings.Contains(string(body), `crawler_requests_total{host="other"} 50`+"\n"))
+}
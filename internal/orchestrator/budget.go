@@ -0,0 +1,202 @@
+package orchestrator
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// budgets that stop the crawl when exhausted
+const (
+	BudgetPages    = "max-pages"
+	BudgetBytes    = "max-bytes"
+	BudgetDuration = "max-duration"
+)
+
+// budget limits the amount of work done by a crawl, zero values are unlimited
+type budget struct {
+	maxPages        int
+	maxPagesPerHost int
+	maxURLsPerPath  int
+	pathDepth       int
+	maxBytes        int64
+	maxDuration     time.Duration
+
+	// work done so far
+	pages     int
+	hostPages map[string]int
+	pathURLs  map[string]int
+	bytes     int64
+
+	// pages being fetched, they hold their share of the budget until they finish
+	pending     int
+	hostPending map[string]int
+	pathPending map[string]int
+
+	// budget that stopped the crawl
+	exhausted string
+}
+
+// SetMaxPages sets the max number of pages fetched
+func SetMaxPages(n int) Option {
+	return func(o *Orchestrator) {
+		o.budget.maxPages = n
+	}
+}
+
+// SetMaxPagesPerHost sets the max number of pages fetched from each host
+// hosts that reach the limit are skipped without stopping the crawl
+func SetMaxPagesPerHost(n int) Option {
+	return func(o *Orchestrator) {
+		o.budget.maxPagesPerHost = n
+	}
+}
+
+// SetMaxURLsPerPath sets the max number of pages fetched under each path
+// the path of a page is its parent directory, so /calendar/2019/01 and /calendar/2019/02 share the /calendar/2019/ path
+// paths that reach the limit are skipped without stopping the crawl
+func SetMaxURLsPerPath(n int) Option {
+	return func(o *Orchestrator) {
+		o.budget.maxURLsPerPath = n
+	}
+}
+
+// SetPathDepth sets the number of directories of the path shared by the pages of the max URLs per path budget
+// with a depth of 1 /calendar/2019/01 and /calendar/2020/01 share the /calendar/ path, zero uses the whole parent directory
+func SetPathDepth(n int) Option {
+	return func(o *Orchestrator) {
+		o.budget.pathDepth = n
+	}
+}
+
+// SetMaxBytes sets the max number of bytes downloaded
+func SetMaxBytes(n int64) Option {
+	return func(o *Orchestrator) {
+		o.budget.maxBytes = n
+	}
+}
+
+// SetMaxDuration sets the max duration of the crawl
+func SetMaxDuration(t time.Duration) Option {
+	return func(o *Orchestrator) {
+		o.budget.maxDuration = t
+	}
+}
+
+// Exhausted returns the budget that stopped the crawl, or an empty string if the crawl was not stopped by a budget
+func (o *Orchestrator) Exhausted() string {
+	return o.budget.exhausted
+}
+
+// allowPage checks if there's budget left to fetch the page
+// the pages being fetched count towards the budget, so the limits are not overshot by the workers
+func (o *Orchestrator) allowPage(u *url.URL) bool {
+	b := &o.budget
+	if b.exhausted != "" {
+		return false
+	}
+	if b.maxPages != 0 && b.pages >= b.maxPages {
+		o.exhaust(BudgetPages)
+		return false
+	}
+	if b.maxPagesPerHost != 0 && b.hostPages[u.Host]+b.hostPending[u.Host] >= b.maxPagesPerHost {
+		o.logger.Debug().Str("url", u.String()).Msg("host budget exhausted, skipping task")
+		return false
+	}
+	path := b.path(u)
+	if b.maxURLsPerPath != 0 && b.pathURLs[path]+b.pathPending[path] >= b.maxURLsPerPath {
+		o.logger.Debug().Str("url", u.String()).Msg("path budget exhausted, skipping task")
+		return false
+	}
+	return true
+}
+
+// hasRoom checks if another page can be fetched without overshooting the max pages
+// pages waiting for room stay queued, as the pages being fetched may still fail
+func (o *Orchestrator) hasRoom() bool {
+	return o.budget.maxPages == 0 || o.budget.pages+o.budget.pending < o.budget.maxPages
+}
+
+// reservePage holds the budget of a page sent to the workers
+func (o *Orchestrator) reservePage(u *url.URL) {
+	b := &o.budget
+	if b.hostPending == nil {
+		b.hostPending = make(map[string]int)
+		b.pathPending = make(map[string]int)
+	}
+	b.pending++
+	b.hostPending[u.Host]++
+	b.pathPending[b.path(u)]++
+}
+
+// releasePage releases the budget held by a page, consuming it if the page was fetched
+func (o *Orchestrator) releasePage(u *url.URL, fetched bool) {
+	b := &o.budget
+	path := b.path(u)
+	b.pending--
+	b.hostPending[u.Host]--
+	b.pathPending[path]--
+	if !fetched {
+		return
+	}
+	if b.hostPages == nil {
+		b.hostPages = make(map[string]int)
+		b.pathURLs = make(map[string]int)
+	}
+	b.pages++
+	b.hostPages[u.Host]++
+	b.pathURLs[path]++
+	if b.maxPages != 0 && b.pages >= b.maxPages {
+		o.exhaust(BudgetPages)
+		o.discardQueued()
+	}
+}
+
+// path returns the path of the page used by the max URLs per path budget
+func (b *budget) path(u *url.URL) string {
+	p := u.EscapedPath()
+	p = p[:strings.LastIndex(p, "/")+1]
+	if b.pathDepth != 0 {
+		if dirs := strings.SplitAfter(p, "/"); len(dirs) > b.pathDepth+1 {
+			p = strings.Join(dirs[:b.pathDepth+1], "")
+		}
+	}
+	return u.Host + p
+}
+
+// consumeBytes consumes the budget of downloaded bytes
+func (o *Orchestrator) consumeBytes(n int) {
+	o.budget.bytes += int64(n)
+	if o.budget.maxBytes != 0 && o.budget.bytes >= o.budget.maxBytes {
+		o.exhaust(BudgetBytes)
+		o.discardQueued()
+	}
+}
+
+// exhaust stops the crawl gracefully because a budget was exhausted
+// no more tasks are queued and the tasks already queued are allowed to finish
+func (o *Orchestrator) exhaust(reason string) {
+	if o.budget.exhausted != "" {
+		return
+	}
+	o.budget.exhausted = reason
+	o.emit(BudgetExhausted{Budget: reason})
+	o.logger.Info().Str("budget", reason).Msg("crawl budget exhausted, finishing crawl")
+}
+
+// discardQueued discards the tasks waiting to be Processed, the tasks being Processed are allowed to finish
+func (o *Orchestrator) discardQueued() {
+	o.next = nil
+	for o.frontier.Len() > 0 {
+		o.frontier.Pop()
+	}
+	for {
+		select {
+		case task := <-o.TaskQueue:
+			o.inProcess -= 1
+			o.releasePage(task.URL, false)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package orchestrator_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrchestrator_budget(t *testing.T) {
+	t.Run("max pages", testOrchestrator_budget_pages)
+	t.Run("max pages failed", testOrchestrator_budget_failed)
+	t.Run("max pages per host", testOrchestrator_budget_host)
+	t.Run("max urls per path depth", testOrchestrator_budget_path)
+	t.Run("max bytes", testOrchestrator_budget_bytes)
+	t.Run("max bytes cached", testOrchestrator_budget_cached)
+	t.Run("max duration", testOrchestrator_budget_duration)
+}
+
+func testOrchestrator_budget_pages(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxPages(2))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: map[string]int{"http://google.com/1": 1, "http://google.com/2": 1}}
+
+	// mock worker loop 2, only one of the children is within budget
+	select {
+	case r := <-o.TaskQueue:
+		o.DoneQueue <- crawler.TaskResult{Task: r, Children: map[string]int{"http://google.com/3": 1}}
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 2)
+	require.Equal(t, orchestrator.BudgetPages, o.Exhausted())
+}
+
+func testOrchestrator_budget_failed(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxPages(2))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: map[string]int{"http://google.com/1": 1, "http://google.com/2": 1}}
+
+	// mock worker loop 2, the failed page does not use up the budget
+	var failed string
+	select {
+	case r := <-o.TaskQueue:
+		failed = r.URL.String()
+		r.Tries = 4
+		err := errors.New("failed")
+		o.ErrorQueue <- crawler.TaskResult{Task: r, Error: &err}
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+
+	// mock worker loop 3, the other child is fetched
+	select {
+	case r := <-o.TaskQueue:
+		require.NotEqual(t, failed, r.URL.String())
+		o.DoneQueue <- crawler.TaskResult{Task: r}
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 2)
+	require.Len(t, o.Failed, 1)
+	require.Equal(t, orchestrator.BudgetPages, o.Exhausted())
+}
+
+func testOrchestrator_budget_host(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxPagesPerHost(1))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: map[string]int{"http://google.com/1": 1, "http://docs.google.com": 1}}
+
+	// mock worker loop 2, only the other host is within budget
+	host1, _ := url.Parse("http://docs.google.com")
+	select {
+	case r := <-o.TaskQueue:
+		require.Equal(t, crawler.Task{URL: host1, Depth: 1, Tries: 0}, r)
+		o.DoneQueue <- crawler.TaskResult{Task: r}
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 2)
+	require.Equal(t, "", o.Exhausted())
+}
+
+func testOrchestrator_budget_path(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxURLsPerPath(1), orchestrator.SetPathDepth(1))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: map[string]int{"http://google.com/calendar/2019/01": 1}}
+
+	// mock worker loop 2, the page of another year shares the /calendar/ path
+	select {
+	case r := <-o.TaskQueue:
+		require.Equal(t, "http://google.com/calendar/2019/01", r.URL.String())
+		o.DoneQueue <- crawler.TaskResult{Task: r, Children: map[string]int{"http://google.com/calendar/2020/01": 1}}
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 2)
+	require.Equal(t, "", o.Exhausted())
+}
+
+func testOrchestrator_budget_bytes(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxBytes(10))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1, the children are not queued
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: map[string]int{"http://google.com/1": 1}, Response: &crawler.Response{Size: 10}}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 1)
+	require.Equal(t, orchestrator.BudgetBytes, o.Exhausted())
+}
+
+func testOrchestrator_budget_cached(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxBytes(10))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1, the page was served from the cache
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: map[string]int{"http://google.com/1": 1}, Response: &crawler.Response{Size: 10, Cache: crawler.CacheRevalidated}}
+
+	// mock worker loop 2, the child is still queued
+	select {
+	case r := <-o.TaskQueue:
+		o.DoneQueue <- crawler.TaskResult{Task: r}
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 2)
+	require.Equal(t, "", o.Exhausted())
+}
+
+func testOrchestrator_budget_duration(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+
+	// start orchestrator, the queued task is discarded once the time is up
+	o := newTestOrchestrator(t, orchestrator.SetMaxDuration(10*time.Millisecond))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	select {
+	case <-o.Done():
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "crawl not finished")
+	}
+	require.Len(t, o.Processed, 0)
+	require.Equal(t, orchestrator.BudgetDuration, o.Exhausted())
+}
@@ -0,0 +1,72 @@
+package orchestrator
+
+import (
+	"net/url"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// importance estimates the importance of the pages while crawling using OPIC (On-line Page Importance Computation)
+// the seed starts with all the cash, which each Processed page distributes to its children in proportion to the
+// times it links to them, the importance of a page is the cash it received over the total cash distributed
+type importance struct {
+	// cash received and not distributed yet
+	cash map[string]float64
+	// cash distributed by each page
+	history map[string]float64
+}
+
+// seed gives the initial cash to the first page
+func (i *importance) seed(u string) {
+	if i.cash == nil {
+		i.cash = make(map[string]float64)
+		i.history = make(map[string]float64)
+	}
+	i.cash[u] = 1
+}
+
+// distribute distributes the cash of a Processed page to its children
+func (i *importance) distribute(result crawler.TaskResult) {
+	if i.cash == nil {
+		return
+	}
+	u := result.URL.String()
+	cash := i.cash[u]
+	i.history[u] += cash
+	i.cash[u] = 0
+
+	var links int
+	for _, n := range result.Children {
+		links += n
+	}
+	if links == 0 {
+		return
+	}
+	for c, n := range result.Children {
+		if parsed, err := url.Parse(c); err == nil {
+			i.cash[parsed.String()] += cash * float64(n) / float64(links)
+		}
+	}
+}
+
+// estimate returns the estimated importance of a page
+func (i *importance) estimate(u string) float64 {
+	return i.history[u] + i.cash[u]
+}
+
+// GetImportance returns the estimated importance of the crawled pages, the scores of all the pages found add up to 1
+func (o *Orchestrator) GetImportance() map[string]float64 {
+	var total float64
+	for u := range o.importance.cash {
+		total += o.importance.estimate(u)
+	}
+	// the Processed pages are the ones that distributed their cash
+	var result = make(map[string]float64)
+	for u := range o.importance.history {
+		result[u] = 0
+		if total != 0 {
+			result[u] = o.importance.estimate(u) / total
+		}
+	}
+	return result
+}
@@ -0,0 +1,45 @@
+package orchestrator_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrchestrator_importance(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t)
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1, the seed links to /a three times and to /b once
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: map[string]int{"http://google.com/a": 3, "http://google.com/b": 1}}
+
+	// mock worker loops 2 and 3, both pages link back to the seed
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-o.TaskQueue:
+			o.DoneQueue <- crawler.TaskResult{Task: r, Children: map[string]int{"http://google.com": 1}}
+		case <-time.After(1 * time.Second):
+			require.FailNow(t, "task not received")
+		}
+	}
+
+	// the seed distributed 1 and got it back, /a distributed 0.75 and /b 0.25
+	<-o.Done()
+	importance := o.GetImportance()
+	require.InDelta(t, 2.0/3, importance["http://google.com"], 0.0001)
+	require.InDelta(t, 0.75/3, importance["http://google.com/a"], 0.0001)
+	require.InDelta(t, 0.25/3, importance["http://google.com/b"], 0.0001)
+}
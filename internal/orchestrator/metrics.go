@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"sync"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// Metrics records the state of the crawl
+type Metrics interface {
+	// SetQueued sets the number of tasks waiting to be processed
+	SetQueued(n int)
+	// SetInProcess sets the number of tasks being processed
+	SetInProcess(n int)
+	// AddProcessed counts a processed page
+	AddProcessed()
+	// AddFailed counts a page that failed after all the retries
+	AddFailed()
+	// AddRetry counts a failed task queued to be retried
+	AddRetry()
+}
+
+// SetMetrics sets the metrics recording the state of the crawl
+func SetMetrics(m Metrics) Option {
+	return func(o *Orchestrator) {
+		o.metrics = m
+	}
+}
+
+// Stats is a snapshot of the progress of the crawl
+type Stats struct {
+	// tasks waiting to be processed
+	Queued int
+	// tasks being processed
+	InProcess int
+	// pages processed and failed after all the retries
+	Processed int
+	Failed    int
+	// failed tasks queued to be retried
+	Retries int
+	// bytes of the response bodies downloaded
+	Bytes int64
+}
+
+// stats is the progress of the crawl, shared with the goroutines reading it
+type stats struct {
+	mu sync.Mutex
+	Stats
+}
+
+// Stats returns the progress of the crawl, it is safe to call while crawling
+func (o *Orchestrator) Stats() Stats {
+	o.stats.mu.Lock()
+	defer o.stats.mu.Unlock()
+	return o.stats.Stats
+}
+
+// record records the size of the queues
+func (o *Orchestrator) record() {
+	queued := o.frontier.Len()
+	if o.next != nil {
+		queued++
+	}
+	o.stats.mu.Lock()
+	o.stats.Queued, o.stats.InProcess = queued, o.inProcess
+	o.stats.mu.Unlock()
+	if o.metrics != nil {
+		o.metrics.SetQueued(queued)
+		o.metrics.SetInProcess(o.inProcess)
+	}
+}
+
+// recordProcessed counts a processed page
+func (o *Orchestrator) recordProcessed(result crawler.TaskResult) {
+	o.stats.mu.Lock()
+	o.stats.Processed++
+	if result.Response != nil {
+		o.stats.Bytes += int64(result.Response.Size)
+	}
+	o.stats.mu.Unlock()
+	if o.metrics != nil {
+		o.metrics.AddProcessed()
+	}
+}
+
+// recordFailed counts a page that failed after all the retries
+func (o *Orchestrator) recordFailed() {
+	o.stats.mu.Lock()
+	o.stats.Failed++
+	o.stats.mu.Unlock()
+	if o.metrics != nil {
+		o.metrics.AddFailed()
+	}
+}
+
+// recordRetry counts a failed task queued to be retried
+func (o *Orchestrator) recordRetry() {
+	o.stats.mu.Lock()
+	o.stats.Retries++
+	o.stats.mu.Unlock()
+	if o.metrics != nil {
+		o.metrics.AddRetry()
+	}
+}
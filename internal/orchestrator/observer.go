@@ -0,0 +1,178 @@
+package orchestrator
+
+import (
+	"sync"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// reasons a task is filtered
+const (
+	FilterDepth  = "depth"
+	FilterHost   = "host"
+	FilterCustom = "custom"
+	FilterTrap   = "trap"
+	FilterBudget = "budget"
+)
+
+// Observer receives the events of the crawl
+// the events are delivered in order on a goroutine of the observer, so a slow observer does not stall the crawl
+type Observer interface {
+	Observe(e Event)
+}
+
+// ObserverFunc is a function observing the events of the crawl
+type ObserverFunc func(e Event)
+
+// Observe calls the function
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+// Event is an event of the crawl, one of the types below
+type Event interface {
+	event()
+}
+
+// TaskQueued is sent when a task is added to the frontier
+type TaskQueued struct {
+	Task crawler.Task
+}
+
+// TaskStarted is sent when a task is sent to the workers
+type TaskStarted struct {
+	Task crawler.Task
+}
+
+// TaskSucceeded is sent when a task is Processed
+type TaskSucceeded struct {
+	Result crawler.TaskResult
+}
+
+// TaskFailed is sent when a task Failed after all the retries
+type TaskFailed struct {
+	Result crawler.TaskResult
+}
+
+// RetryScheduled is sent when a Failed task is queued again
+type RetryScheduled struct {
+	Result crawler.TaskResult
+}
+
+// TaskFiltered is sent when a link is not queued, with the reason
+type TaskFiltered struct {
+	URL    string
+	Depth  int
+	Reason string
+}
+
+// BudgetExhausted is sent when a budget stops the crawl
+type BudgetExhausted struct {
+	Budget string
+}
+
+// CrawlFinished is sent when there are no tasks left
+type CrawlFinished struct {
+	Stats Stats
+	// budget that stopped the crawl, if any
+	Exhausted string
+}
+
+func (TaskQueued) event()      {}
+func (TaskStarted) event()     {}
+func (TaskSucceeded) event()   {}
+func (TaskFailed) event()      {}
+func (RetryScheduled) event()  {}
+func (TaskFiltered) event()    {}
+func (BudgetExhausted) event() {}
+func (CrawlFinished) event()   {}
+
+// observerBuffer is the number of events buffered for each observer before the progress events are dropped
+// past it, the events only reporting progress (TaskQueued, TaskStarted, TaskFiltered and RetryScheduled) are dropped,
+// while the others are still buffered so no result is ever lost, and the crawl never waits for a slow observer
+const observerBuffer = 1024
+
+// AddObserver adds an observer of the events of the crawl
+// the results waiting for a slow observer are kept in memory, and Stop waits for them to be delivered
+func AddObserver(obs Observer) Option {
+	return func(o *Orchestrator) {
+		ob := observer{observer: obs, done: make(chan struct{})}
+		ob.ready = sync.NewCond(&ob.mu)
+		o.observers = append(o.observers, &ob)
+	}
+}
+
+// observer buffers the events of an observer so they are sent without blocking
+type observer struct {
+	observer Observer
+
+	mu      sync.Mutex
+	events  []Event
+	closed  bool
+	dropped int
+	// signals there are events waiting or the observer was closed
+	ready *sync.Cond
+	// closed once all the events were delivered
+	done chan struct{}
+}
+
+// run delivers the events until the observer is closed
+func (ob *observer) run() {
+	defer close(ob.done)
+	for {
+		ob.mu.Lock()
+		for len(ob.events) == 0 && !ob.closed {
+			ob.ready.Wait()
+		}
+		events, closed := ob.events, ob.closed
+		ob.events = nil
+		ob.mu.Unlock()
+		for _, e := range events {
+			ob.observer.Observe(e)
+		}
+		if closed && len(events) == 0 {
+			return
+		}
+	}
+}
+
+// send buffers an event without blocking, dropping the progress events when the buffer is full
+func (ob *observer) send(e Event) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	if ob.closed {
+		return
+	}
+	if len(ob.events) >= observerBuffer && progress(e) {
+		ob.dropped++
+		return
+	}
+	ob.events = append(ob.events, e)
+	ob.ready.Signal()
+}
+
+// close waits for the events buffered to be delivered and stops the observer, returning the number of events dropped
+func (ob *observer) close() int {
+	ob.mu.Lock()
+	ob.closed = true
+	ob.ready.Signal()
+	ob.mu.Unlock()
+	<-ob.done
+	return ob.dropped
+}
+
+// progress checks if the event only reports the progress of the crawl, so it can be dropped
+func progress(e Event) bool {
+	switch e.(type) {
+	case TaskQueued, TaskStarted, TaskFiltered, RetryScheduled:
+		return true
+	}
+	return false
+}
+
+// emit sends an event to all the observers
+func (o *Orchestrator) emit(e Event) {
+	for _, ob := range o.observers {
+		ob.send(e)
+	}
+}
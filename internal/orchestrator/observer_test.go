@@ -0,0 +1,140 @@
+package orchestrator_test
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrchestrator_observer(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+
+	// the observer is blocked until the crawl is finished
+	release := make(chan struct{})
+	events := make(chan orchestrator.Event, 100)
+	observer := orchestrator.ObserverFunc(func(e orchestrator.Event) {
+		<-release
+		events <- e
+	})
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.AddObserver(observer), orchestrator.AddSudDomainFilters("google.com"), orchestrator.SetMaxDepth(1))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop, the child fails once and links to a page too deep
+	receive := func() crawler.Task {
+		select {
+		case r := <-o.TaskQueue:
+			r.Tries++
+			return r
+		case <-time.After(1 * time.Second):
+			require.FailNow(t, "task not received")
+		}
+		return crawler.Task{}
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: receive(), Children: map[string]int{"http://google.com/1": 1, "http://monzo.com": 1}}
+	err := errors.New("timeout")
+	o.ErrorQueue <- crawler.TaskResult{Task: receive(), Error: &err}
+	o.DoneQueue <- crawler.TaskResult{Task: receive(), Children: map[string]int{"http://google.com/1/2": 1}}
+	select {
+	case <-o.Done():
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "crawl not finished")
+	}
+
+	// the events buffered are delivered before the orchestrator is stopped
+	close(release)
+	o.Stop()
+	var received []string
+	for len(events) > 0 {
+		e := <-events
+		name := fmt.Sprintf("%T", e)
+		if f, ok := e.(orchestrator.TaskFiltered); ok {
+			name += " " + f.URL + " " + f.Reason
+		}
+		if finished, ok := e.(orchestrator.CrawlFinished); ok {
+			require.Equal(t, 2, finished.Stats.Processed)
+			require.Equal(t, 1, finished.Stats.Retries)
+		}
+		received = append(received, name)
+	}
+
+	// the events of each result are in order, the events of the children of a page are in no particular order
+	require.Equal(t, [][]string{
+		{"orchestrator.TaskQueued", "orchestrator.TaskStarted"},
+		{"orchestrator.TaskFiltered http://monzo.com host", "orchestrator.TaskQueued", "orchestrator.TaskStarted", "orchestrator.TaskSucceeded"},
+		{"orchestrator.RetryScheduled", "orchestrator.TaskStarted"},
+		{"orchestrator.TaskFiltered http://google.com/1/2 depth", "orchestrator.TaskSucceeded"},
+		{"orchestrator.CrawlFinished"},
+	}, phases(received))
+}
+
+func TestOrchestrator_observerBlocked(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	pages := 2000
+
+	// the observer is blocked until all the pages are crawled
+	release := make(chan struct{})
+	var succeeded int
+	observer := orchestrator.ObserverFunc(func(e orchestrator.Event) {
+		<-release
+		if _, ok := e.(orchestrator.TaskSucceeded); ok {
+			succeeded++
+		}
+	})
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.AddObserver(observer), orchestrator.AddSudDomainFilters("google.com"))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop, each page links to the next one, the tasks are still dispatched past the buffer of the observer
+	for i := 1; i <= pages; i++ {
+		select {
+		case r := <-o.TaskQueue:
+			r.Tries++
+			children := map[string]int{fmt.Sprintf("http://google.com/%d", i): 1}
+			if i == pages {
+				children = nil
+			}
+			o.DoneQueue <- crawler.TaskResult{Task: r, Children: children}
+		case <-time.After(1 * time.Second):
+			require.FailNow(t, "task not received", "page %d", i)
+		}
+	}
+	select {
+	case <-o.Done():
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "crawl not finished")
+	}
+
+	// no result is lost
+	close(release)
+	o.Stop()
+	require.Equal(t, pages, succeeded)
+}
+
+// phases splits the events by the results that caused them, sorting the events of each result
+func phases(events []string) [][]string {
+	var result [][]string
+	var phase []string
+	for _, e := range events {
+		switch e {
+		case "orchestrator.TaskSucceeded", "orchestrator.RetryScheduled", "orchestrator.CrawlFinished":
+			sort.Strings(phase)
+			result = append(result, phase)
+			phase = nil
+		}
+		phase = append(phase, e)
+	}
+	sort.Strings(phase)
+	return append(result, phase)
+}
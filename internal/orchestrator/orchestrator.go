@@ -2,7 +2,6 @@ package orchestrator
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/url"
 	"strings"
@@ -23,11 +22,14 @@ type Orchestrator struct {
 	// inbound error channel with tasks that Failed to be Processed
 	ErrorQueue chan crawler.TaskResult
 
-	// all the tasks Processed
+	// all the tasks Processed, empty when the pages are saved to a storage
 	Processed map[string]crawler.TaskResult
-	// all the tasks that Failed to be Processed
+	// all the tasks that Failed to be Processed, empty when the pages are saved to a storage
 	Failed map[string]crawler.TaskResult
 
+	// additional hosts the crawl starts from
+	seeds []string
+
 	// max number of retries for each Failed task
 	maxRetry int
 	// max depth of the tree
@@ -38,8 +40,34 @@ type Orchestrator struct {
 	sudDomainFilters []string
 	filters          []Filter
 
+	// tasks waiting to be Processed
+	frontier Scheduler
+	// next task to be sent to the TaskQueue
+	next *crawler.Task
 	// number of tasks being Processed at this moment
 	inProcess int
+	// urls already queued to be Processed
+	queued map[string]struct{}
+
+	// limits of the crawl
+	budget budget
+	// crawler trap detection
+	traps traps
+	// importance of the pages
+	importance importance
+	// storage of the crawled pages and the pages waiting to be saved
+	storage Storage
+	saveCh  chan crawler.TaskResult
+	// closed to stop accepting pages, and once the pages waiting are saved
+	saveStop chan struct{}
+	savedCh  chan struct{}
+	// pages sent once the storage was stopped
+	unsaved int64
+	// metrics and progress of the crawl
+	metrics Metrics
+	stats   stats
+	// observers of the events of the crawl
+	observers []*observer
 
 	// gracefully shutdown orchestrator
 	ctx    context.Context
@@ -67,6 +95,8 @@ func New(logger *zerolog.Logger, size int, opts ...Option) *Orchestrator {
 
 		Processed: make(map[string]crawler.TaskResult),
 		Failed:    make(map[string]crawler.TaskResult),
+		queued:    make(map[string]struct{}),
+		frontier:  NewBreadthFirst(),
 	}
 	for _, opt := range opts {
 		opt(&w)
@@ -88,6 +118,13 @@ func SetMaxDepth(n int) Option {
 	}
 }
 
+// AddSeed adds a host the crawl starts from, along with the host provided to Start
+func AddSeed(host string) Option {
+	return func(o *Orchestrator) {
+		o.seeds = append(o.seeds, host)
+	}
+}
+
 // AddExactHostFilter adds a host name to filter tasks
 // hosts added are whitelisted if there's an exact match on the provided host
 func AddExactHostFilter(host string) Option {
@@ -114,14 +151,53 @@ func AddCustomFilter(filter Filter) Option {
 	}
 }
 
+// logoutPatterns are the path segments that identify logout links
+var logoutPatterns = map[string]struct{}{
+	"logout": {}, "log-out": {}, "log_out": {}, "logoff": {}, "log-off": {}, "signout": {}, "sign-out": {}, "sign_out": {},
+}
+
+// LogoutFilter filters out logout links so an authenticated session is not killed while crawling
+// a link is a logout link when a whole path segment, without its extension, or a query key or value is a logout pattern
+func LogoutFilter(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return true
+	}
+	for _, segment := range strings.Split(strings.ToLower(parsed.Path), "/") {
+		if i := strings.LastIndex(segment, "."); i > 0 {
+			segment = segment[:i]
+		}
+		if _, found := logoutPatterns[segment]; found {
+			return false
+		}
+	}
+	for key, values := range parsed.Query() {
+		for _, v := range append(values, key) {
+			if _, found := logoutPatterns[strings.ToLower(v)]; found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // Start starts processing TaskQueue
 func (o *Orchestrator) Start(host string) error {
 	if o.ctx != nil {
 		return errors.New("orchestrator already started")
 	}
 
-	// enqueue the first task
-	o.queueHost(host, 0)
+	for _, ob := range o.observers {
+		go ob.run()
+	}
+
+	// enqueue the first tasks
+	for _, h := range append([]string{host}, o.seeds...) {
+		if u, err := url.Parse(h); err == nil {
+			o.importance.seed(u.String())
+		}
+		o.queueHost(h, 0)
+	}
 
 	// start orchestrator
 	ctx, cancel := context.WithCancel(context.Background())
@@ -129,13 +205,14 @@ func (o *Orchestrator) Start(host string) error {
 	o.cancel = cancel
 	o.stopCh = make(chan struct{})
 	o.doneCh = make(chan struct{})
+	o.startStorage()
 	go o.run()
 	return nil
 }
 
-// Stop stops the worker
-func (o Orchestrator) Stop() {
-	if o.ctx == nil {
+// Stop stops the orchestrator, once the observers received the events of the crawl
+func (o *Orchestrator) Stop() {
+	if o.ctx == nil || o.ctx.Err() != nil {
 		return
 	}
 	// stop the orchestrator
@@ -145,26 +222,55 @@ func (o Orchestrator) Stop() {
 	// wait for the orchestrator to be gracefully stopped
 	select {
 	case <-o.stopCh:
-		return
 	case <-time.After(10 * time.Second):
-		return
+		o.logger.Warn().Msg("orchestrator not stopped in time")
+	}
+	o.stopStorage()
+	for _, ob := range o.observers {
+		if dropped := ob.close(); dropped > 0 {
+			o.logger.Warn().Int("dropped", dropped).Msg("observer too slow, progress events dropped")
+		}
 	}
 }
 
 // Done waits until the crawling is finished
-func (o Orchestrator) Done() <-chan struct{} {
+func (o *Orchestrator) Done() <-chan struct{} {
 	return o.doneCh
 }
 
 // run is the main execution loop of the worker
 func (o *Orchestrator) run() {
 	o.logger.Info().Msg("orchestrator started...")
+	var deadline <-chan time.Time
+	if o.budget.maxDuration != 0 {
+		deadline = time.After(o.budget.maxDuration)
+	}
 	for {
 		o.checkFinished()
+
+		// only send a task when there's one waiting, a nil channel blocks forever
+		var taskQueue chan crawler.Task
+		if o.next == nil {
+			o.next = o.pop()
+		}
+		if o.next != nil {
+			taskQueue = o.TaskQueue
+		}
+		o.record()
+
 		select {
+		case taskQueue <- o.dispatch():
+			o.emit(TaskStarted{Task: *o.next})
+			o.reservePage(o.next.URL)
+			o.next = nil
+			o.inProcess += 1
+		case <-deadline:
+			o.exhaust(BudgetDuration)
+			o.discardQueued()
 		case <-o.ctx.Done():
 			o.logger.Info().Msg("orchestrator stopping...")
-			o.stopCh <- struct{}{}
+			o.drain()
+			close(o.stopCh)
 			return
 		case task, ok := <-o.DoneQueue:
 			if ok {
@@ -181,31 +287,51 @@ func (o *Orchestrator) run() {
 // handleTask handles successfully  Processed tasks
 func (o *Orchestrator) handleTask(result crawler.TaskResult) {
 	o.inProcess -= 1
+	o.releasePage(result.URL, true)
 	// add the task to the Processed cache
-	o.Processed[result.URL.String()] = result
+	o.keep(o.Processed, result)
+	o.recordProcessed(result)
+	o.emit(TaskSucceeded{Result: result})
+	// pages served from the cache are not downloaded again
+	if result.Response != nil && result.Response.Cache == "" {
+		o.consumeBytes(result.Response.Size)
+	}
+	o.detectSimilarLinks(result.Children)
+
+	// count the links between the pages
+	o.importance.distribute(result)
+	if s, ok := o.frontier.(linkScheduler); ok {
+		for u := range result.Children {
+			if c, err := url.Parse(u); err == nil {
+				s.Inlink(c.String(), o.importance.estimate(c.String()))
+			}
+		}
+	}
 
-	// check if the children have been Processed already
+	// check if the children have been queued already
 	for u, _ := range result.Children {
-		if _, found := o.Processed[u]; !found {
+		if _, found := o.queued[u]; !found {
 			// check if the children should be Processed based on filters
-			if o.applyFilters(u) {
+			if reason := o.applyFilters(u); reason == "" {
 				o.queueHost(u, result.Depth+1)
+			} else {
+				o.emit(TaskFiltered{URL: u, Depth: result.Depth + 1, Reason: reason})
 			}
 		}
 	}
 }
 
-// applyFilters checks if the task should be Processed using the filters
-func (o *Orchestrator) applyFilters(u string) bool {
+// applyFilters checks if the task should be Processed using the filters, returning the filter that rejected it
+func (o *Orchestrator) applyFilters(u string) string {
 	if !o.applyHostFilters(u) {
-		return false
+		return FilterHost
 	}
 	for _, f := range o.filters {
 		if !f(u) {
-			return false
+			return FilterCustom
 		}
 	}
-	return true
+	return ""
 }
 
 // applyHostFilters checks if the task should be Processed using the default host filters
@@ -230,54 +356,110 @@ func (o *Orchestrator) applyHostFilters(u string) bool {
 
 // queueHost creates a new task and schedules it to be Processed
 func (o *Orchestrator) queueHost(u string, depth int) {
+	host, err := url.Parse(u)
+	if err != nil {
+		return
+	}
+	// dont queue the same task twice
+	if _, found := o.queued[host.String()]; found {
+		return
+	}
 	// dont queue task if we hit the depth limit
 	if o.maxDepth != 0 && depth > o.maxDepth {
+		o.emit(TaskFiltered{URL: host.String(), Depth: depth, Reason: FilterDepth})
 		return
 	}
-	host, err := url.Parse(u)
-	if err != nil {
+	if !o.allowTrap(host) {
+		o.emit(TaskFiltered{URL: host.String(), Depth: depth, Reason: FilterTrap})
 		return
 	}
-	o.processTask(crawler.Task{URL: host, Depth: depth})
+	if !o.allowPage(host) {
+		o.emit(TaskFiltered{URL: host.String(), Depth: depth, Reason: FilterBudget})
+		return
+	}
+	o.queued[host.String()] = struct{}{}
+	task := crawler.Task{URL: host, Depth: depth}
+	o.processTask(task)
+	o.emit(TaskQueued{Task: task})
 }
 
 // handleFailed handles tasks that Failed to be Processed
 func (o *Orchestrator) handleFailed(result crawler.TaskResult) {
 	o.inProcess -= 1
-	if result.Tries > o.maxRetry {
+	o.releasePage(result.URL, false)
+	// tasks interrupted by stopping the workers are queued again without counting as a retry
+	if result.Error != nil && errors.Is(*result.Error, context.Canceled) {
+		o.processTask(result.Task)
+		o.emit(TaskQueued{Task: result.Task})
+		return
+	}
+	if result.Tries > o.maxRetry || o.budget.exhausted != "" {
 		// add the task to the Failed cache
-		o.Failed[result.URL.String()] = result
+		o.keep(o.Failed, result)
+		o.recordFailed()
+		o.emit(TaskFailed{Result: result})
 		return
 	}
 	// retry the task
+	o.recordRetry()
+	o.emit(RetryScheduled{Result: result})
 	o.processTask(result.Task)
 }
 
-// processTask queues a task to be Processed
-func (o *Orchestrator) processTask(task crawler.Task) {
-	o.TaskQueue <- task
-	o.inProcess += 1
+// drain handles the results left in the queues and returns the tasks not received by the workers to the frontier
+func (o *Orchestrator) drain() {
+	for {
+		select {
+		case result := <-o.DoneQueue:
+			o.handleTask(result)
+		case result := <-o.ErrorQueue:
+			o.handleFailed(result)
+		case task := <-o.TaskQueue:
+			o.inProcess -= 1
+			o.releasePage(task.URL, false)
+			o.processTask(task)
+		default:
+			o.record()
+			return
+		}
+	}
 }
 
-// checkFinished checks if all the tasks have been Processed
-func (o *Orchestrator) checkFinished() {
-	// there are no tasks being Processed
-	if o.inProcess == 0 {
-		o.doneCh <- struct{}{}
+// pop returns the next task of the frontier that is within the budget, or nil if there's none
+func (o *Orchestrator) pop() *crawler.Task {
+	for o.hasRoom() {
+		task, ok := o.frontier.Pop()
+		if !ok {
+			return nil
+		}
+		// the budget may have been used up since the task was queued
+		if o.allowPage(task.URL) {
+			return &task
+		}
+		o.emit(TaskFiltered{URL: task.URL.String(), Depth: task.Depth, Reason: FilterBudget})
 	}
+	return nil
+}
+
+// processTask queues a task to be Processed
+func (o *Orchestrator) processTask(task crawler.Task) {
+	o.frontier.Push(task)
 }
 
-// GetHits returns the crawled pages
-func (o *Orchestrator) GetHits() map[string]map[string]int {
-	var result = make(map[string]map[string]int)
-	for _, p := range o.Processed {
-		result[p.URL.String()] = p.Children
+// dispatch returns the next task to be sent to the TaskQueue
+func (o *Orchestrator) dispatch() crawler.Task {
+	if o.next == nil {
+		return crawler.Task{}
 	}
-	return result
+	return *o.next
 }
 
-// GetJson returns a json formatted version of the crawled pages
-func (o *Orchestrator) GetJson() string {
-	j, _ := json.Marshal(o.GetHits())
-	return string(j)
+// checkFinished checks if all the tasks have been Processed
+func (o *Orchestrator) checkFinished() {
+	// there are no tasks being Processed or waiting to be Processed
+	if o.inProcess == 0 && o.next == nil && o.frontier.Len() == 0 {
+		o.record()
+		o.emit(CrawlFinished{Stats: o.Stats(), Exhausted: o.budget.exhausted})
+		o.doneCh <- struct{}{}
+	}
 }
@@ -1,6 +1,7 @@
 package orchestrator_test
 
 import (
+	"context"
 	"errors"
 	"net/url"
 	"testing"
@@ -291,3 +292,52 @@ func TestOrchestrator_failed(t *testing.T) {
 	}
 	require.Equal(t, expectedF, o.Failed)
 }
+
+func TestOrchestrator_interrupted(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	err := context.Canceled
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxRetries(0))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// the interrupted task is queued again without counting as a retry
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-o.TaskQueue:
+			require.Equal(t, crawler.Task{URL: host, Depth: 0, Tries: 0}, r)
+		case <-time.After(1 * time.Second):
+			require.FailNow(t, "task not received")
+		}
+		o.ErrorQueue <- crawler.TaskResult{Task: crawler.Task{URL: host, Depth: 0, Tries: 0}, Error: &err}
+	}
+	select {
+	case r := <-o.TaskQueue:
+		require.Equal(t, crawler.Task{URL: host, Depth: 0, Tries: 0}, r)
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: crawler.Task{URL: host, Depth: 0, Tries: 1}}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 1)
+	require.Empty(t, o.Failed)
+	require.Equal(t, 0, o.Stats().Retries)
+}
+
+func TestLogoutFilter(t *testing.T) {
+	for u, allowed := range map[string]bool{
+		"http://google.com/logout":               false,
+		"http://google.com/account/Sign-Out/":    false,
+		"http://google.com/logout.php":           false,
+		"http://google.com/index?action=logout":  false,
+		"http://google.com/index?logoff=1":       false,
+		"http://google.com/blog/logout-tips":     true,
+		"http://google.com/signout-feature":      true,
+		"http://google.com/page?q=how+to+logout": true,
+		"http://google.com/login":                true,
+	} {
+		require.Equal(t, allowed, orchestrator.LogoutFilter(u), u)
+	}
+}
@@ -0,0 +1,277 @@
+package orchestrator
+
+import (
+	"container/heap"
+	"errors"
+	"regexp"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// ErrUnknownStrategy is returned when the crawl ordering strategy is not known
+var ErrUnknownStrategy = errors.New("unknown strategy, expected bfs, dfs, best-first, opic or round-robin")
+
+// crawl ordering strategies
+const (
+	StrategyBFS        = "bfs"
+	StrategyDFS        = "dfs"
+	StrategyBestFirst  = "best-first"
+	StrategyOPIC       = "opic"
+	StrategyRoundRobin = "round-robin"
+)
+
+// Scheduler orders the tasks waiting to be Processed
+type Scheduler interface {
+	// Push adds a task to be Processed
+	Push(task crawler.Task)
+	// Pop removes the next task to be Processed, returns false if there are no tasks waiting
+	Pop() (crawler.Task, bool)
+	// Len returns the number of tasks waiting to be Processed
+	Len() int
+}
+
+// linkScheduler is implemented by the schedulers that take the links between pages into account
+type linkScheduler interface {
+	// Inlink counts a link to the url, importance is the importance of the url estimated so far
+	Inlink(u string, importance float64)
+}
+
+// ScoreFunc scores a task for the best-first strategy, tasks with higher scores are Processed first
+// inlinks is the number of Processed pages linking to the task so far and importance is its estimated importance
+type ScoreFunc func(task crawler.Task, inlinks int, importance float64) float64
+
+// SetScheduler sets the scheduler that orders the tasks, by default tasks are Processed breadth-first
+func SetScheduler(s Scheduler) Option {
+	return func(o *Orchestrator) {
+		o.frontier = s
+	}
+}
+
+// NewScheduler instantiates the scheduler of a strategy, the score is only used by the best-first strategy
+func NewScheduler(strategy string, score ScoreFunc) (Scheduler, error) {
+	switch strategy {
+	case StrategyBFS:
+		return NewBreadthFirst(), nil
+	case StrategyDFS:
+		return NewDepthFirst(), nil
+	case StrategyBestFirst:
+		return NewBestFirst(score), nil
+	case StrategyOPIC:
+		return NewBestFirst(ImportanceScore), nil
+	case StrategyRoundRobin:
+		return NewRoundRobin(), nil
+	default:
+		return nil, ErrUnknownStrategy
+	}
+}
+
+// NewBreadthFirst instantiates a scheduler that processes the shallowest tasks first
+func NewBreadthFirst() Scheduler {
+	return newPriorityQueue(func(a, b *item) bool {
+		if a.task.Depth != b.task.Depth {
+			return a.task.Depth < b.task.Depth
+		}
+		return a.seq < b.seq
+	})
+}
+
+// NewDepthFirst instantiates a scheduler that processes the deepest tasks first
+func NewDepthFirst() Scheduler {
+	return newPriorityQueue(func(a, b *item) bool {
+		if a.task.Depth != b.task.Depth {
+			return a.task.Depth > b.task.Depth
+		}
+		return a.seq > b.seq
+	})
+}
+
+// bestFirst is a scheduler that processes the tasks with the highest score first
+type bestFirst struct {
+	*priorityQueue
+	score      ScoreFunc
+	inlinks    map[string]int
+	importance map[string]float64
+}
+
+// NewBestFirst instantiates a scheduler that processes the tasks with the highest score first
+// the tasks are rescored when new links to them are found, by default the tasks are scored by their inlinks
+func NewBestFirst(score ScoreFunc) Scheduler {
+	if score == nil {
+		score = InlinkScore
+	}
+	s := bestFirst{score: score, inlinks: make(map[string]int), importance: make(map[string]float64)}
+	s.priorityQueue = newPriorityQueue(func(a, b *item) bool {
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.seq < b.seq
+	})
+	return &s
+}
+
+// Push adds a task to be Processed
+func (s *bestFirst) Push(task crawler.Task) {
+	u := task.URL.String()
+	s.push(task, s.score(task, s.inlinks[u], s.importance[u]))
+}
+
+// Inlink counts a link to the url and rescores it if it's waiting to be Processed
+func (s *bestFirst) Inlink(u string, importance float64) {
+	s.inlinks[u]++
+	s.importance[u] = importance
+	if it, found := s.index[u]; found {
+		it.score = s.score(it.task, s.inlinks[u], importance)
+		heap.Fix((*priorityHeap)(s.priorityQueue), it.pos)
+	}
+}
+
+// InlinkScore scores the tasks by the number of pages linking to them
+func InlinkScore(_ crawler.Task, inlinks int, _ float64) float64 {
+	return float64(inlinks)
+}
+
+// ImportanceScore scores the tasks by their estimated importance
+func ImportanceScore(_ crawler.Task, _ int, importance float64) float64 {
+	return importance
+}
+
+// PatternScore scores the tasks by the weights of the url patterns they match plus their inlinks
+func PatternScore(weights map[string]float64) (ScoreFunc, error) {
+	var patterns = make(map[*regexp.Regexp]float64)
+	for p, w := range weights {
+		r, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		patterns[r] = w
+	}
+	return func(task crawler.Task, inlinks int, _ float64) float64 {
+		score := float64(inlinks)
+		for r, w := range patterns {
+			if r.MatchString(task.URL.String()) {
+				score += w
+			}
+		}
+		return score
+	}, nil
+}
+
+// roundRobin is a scheduler that alternates between the hosts, the tasks of each host are Processed in order
+type roundRobin struct {
+	hosts  []string
+	queues map[string][]crawler.Task
+	next   int
+	size   int
+}
+
+// NewRoundRobin instantiates a scheduler that alternates between the hosts
+func NewRoundRobin() Scheduler {
+	return &roundRobin{queues: make(map[string][]crawler.Task)}
+}
+
+// Push adds a task to be Processed
+func (s *roundRobin) Push(task crawler.Task) {
+	h := task.URL.Host
+	if _, found := s.queues[h]; !found {
+		s.hosts = append(s.hosts, h)
+	}
+	s.queues[h] = append(s.queues[h], task)
+	s.size++
+}
+
+// Pop removes the next task to be Processed, returns false if there are no tasks waiting
+func (s *roundRobin) Pop() (crawler.Task, bool) {
+	if s.size == 0 {
+		return crawler.Task{}, false
+	}
+	if s.next >= len(s.hosts) {
+		s.next = 0
+	}
+	h := s.hosts[s.next]
+	task := s.queues[h][0]
+	s.queues[h] = s.queues[h][1:]
+	s.size--
+	if len(s.queues[h]) == 0 {
+		// the host has no more tasks, the next host takes its place
+		delete(s.queues, h)
+		s.hosts = append(s.hosts[:s.next], s.hosts[s.next+1:]...)
+	} else {
+		s.next++
+	}
+	return task, true
+}
+
+// Len returns the number of tasks waiting to be Processed
+func (s *roundRobin) Len() int {
+	return s.size
+}
+
+// item is a task waiting in a priority queue
+type item struct {
+	task  crawler.Task
+	score float64
+	seq   int
+	pos   int
+}
+
+// priorityQueue is a scheduler ordering the tasks with a less function
+type priorityQueue struct {
+	items []*item
+	index map[string]*item
+	less  func(a, b *item) bool
+	seq   int
+}
+
+// newPriorityQueue instantiates a new priority queue
+func newPriorityQueue(less func(a, b *item) bool) *priorityQueue {
+	return &priorityQueue{index: make(map[string]*item), less: less}
+}
+
+// Push adds a task to be Processed
+func (q *priorityQueue) Push(task crawler.Task) {
+	q.push(task, 0)
+}
+
+// Pop removes the next task to be Processed, returns false if there are no tasks waiting
+func (q *priorityQueue) Pop() (crawler.Task, bool) {
+	if len(q.items) == 0 {
+		return crawler.Task{}, false
+	}
+	it := heap.Pop((*priorityHeap)(q)).(*item)
+	delete(q.index, it.task.URL.String())
+	return it.task, true
+}
+
+// Len returns the number of tasks waiting to be Processed
+func (q *priorityQueue) Len() int {
+	return len(q.items)
+}
+
+// push adds a task with a score to the queue
+func (q *priorityQueue) push(task crawler.Task, score float64) {
+	it := &item{task: task, score: score, seq: q.seq}
+	q.seq++
+	q.index[task.URL.String()] = it
+	heap.Push((*priorityHeap)(q), it)
+}
+
+// priorityHeap implements heap.Interface for the priority queue
+type priorityHeap priorityQueue
+
+func (h *priorityHeap) Len() int           { return len(h.items) }
+func (h *priorityHeap) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h *priorityHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].pos = i
+	h.items[j].pos = j
+}
+func (h *priorityHeap) Push(x interface{}) {
+	it := x.(*item)
+	it.pos = len(h.items)
+	h.items = append(h.items, it)
+}
+func (h *priorityHeap) Pop() interface{} {
+	it := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return it
+}
@@ -0,0 +1,97 @@
+package orchestrator_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"github.com/stretchr/testify/require"
+)
+
+func newTask(t *testing.T, u string, depth int) crawler.Task {
+	parsed, err := url.Parse(u)
+	require.Nil(t, err)
+	return crawler.Task{URL: parsed, Depth: depth}
+}
+
+func popAll(s orchestrator.Scheduler) []string {
+	var result []string
+	for s.Len() > 0 {
+		task, _ := s.Pop()
+		result = append(result, task.URL.String())
+	}
+	return result
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("breadth first", testScheduler_bfs)
+	t.Run("depth first", testScheduler_dfs)
+	t.Run("best first", testScheduler_best)
+	t.Run("opic", testScheduler_opic)
+	t.Run("round robin", testScheduler_roundRobin)
+	t.Run("unknown strategy", testScheduler_unknown)
+}
+
+func testScheduler_bfs(t *testing.T) {
+	s := orchestrator.NewBreadthFirst()
+	s.Push(newTask(t, "http://google.com/a/b", 2))
+	s.Push(newTask(t, "http://google.com/a", 1))
+	s.Push(newTask(t, "http://google.com/c", 1))
+	require.Equal(t, []string{"http://google.com/a", "http://google.com/c", "http://google.com/a/b"}, popAll(s))
+
+	_, ok := s.Pop()
+	require.False(t, ok)
+}
+
+func testScheduler_dfs(t *testing.T) {
+	s := orchestrator.NewDepthFirst()
+	s.Push(newTask(t, "http://google.com/a", 1))
+	s.Push(newTask(t, "http://google.com/a/b", 2))
+	s.Push(newTask(t, "http://google.com/c", 1))
+	require.Equal(t, []string{"http://google.com/a/b", "http://google.com/c", "http://google.com/a"}, popAll(s))
+}
+
+func testScheduler_best(t *testing.T) {
+	score, err := orchestrator.PatternScore(map[string]float64{"/blog/": 10})
+	require.Nil(t, err)
+	s := orchestrator.NewBestFirst(score)
+	s.Push(newTask(t, "http://google.com/a", 1))
+	s.Push(newTask(t, "http://google.com/b", 1))
+	s.Push(newTask(t, "http://google.com/blog/c", 1))
+
+	// the tasks are rescored with the new links
+	s.(interface{ Inlink(string, float64) }).Inlink("http://google.com/b", 0)
+	require.Equal(t, []string{"http://google.com/blog/c", "http://google.com/b", "http://google.com/a"}, popAll(s))
+
+	_, err = orchestrator.PatternScore(map[string]float64{"(": 1})
+	require.NotNil(t, err)
+}
+
+func testScheduler_opic(t *testing.T) {
+	s, err := orchestrator.NewScheduler(orchestrator.StrategyOPIC, nil)
+	require.Nil(t, err)
+	s.Push(newTask(t, "http://google.com/a", 1))
+	s.Push(newTask(t, "http://google.com/b", 1))
+
+	// the tasks are rescored with their importance
+	s.(interface{ Inlink(string, float64) }).Inlink("http://google.com/b", 0.5)
+	require.Equal(t, []string{"http://google.com/b", "http://google.com/a"}, popAll(s))
+}
+
+func testScheduler_roundRobin(t *testing.T) {
+	s := orchestrator.NewRoundRobin()
+	s.Push(newTask(t, "http://google.com/a", 1))
+	s.Push(newTask(t, "http://google.com/b", 1))
+	s.Push(newTask(t, "http://google.com/c", 1))
+	s.Push(newTask(t, "http://docs.google.com/a", 1))
+	s.Push(newTask(t, "http://mail.google.com/a", 1))
+	require.Equal(t, []string{
+		"http://google.com/a", "http://docs.google.com/a", "http://mail.google.com/a", "http://google.com/b", "http://google.com/c",
+	}, popAll(s))
+}
+
+func testScheduler_unknown(t *testing.T) {
+	_, err := orchestrator.NewScheduler("random", nil)
+	require.Equal(t, orchestrator.ErrUnknownStrategy, err)
+}
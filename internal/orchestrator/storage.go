@@ -0,0 +1,101 @@
+package orchestrator
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+const (
+	// storageBuffer is the number of pages waiting to be saved before the crawl waits for the storage
+	storageBuffer = 100
+	// storageTimeout is how long stopping waits for the pages waiting to be saved
+	storageTimeout = 10 * time.Second
+)
+
+// Storage persists the crawled pages as they are processed, or once they fail for good
+type Storage interface {
+	Save(result crawler.TaskResult) error
+}
+
+// SetStorage sets the storage where the crawled pages are saved
+// the pages are saved on their own goroutine, and they are not kept in Processed and Failed, so a large crawl doesn't
+// hold all its pages in memory
+func SetStorage(s Storage) Option {
+	return func(o *Orchestrator) {
+		o.storage = s
+	}
+}
+
+// startStorage starts saving the pages sent to the storage
+func (o *Orchestrator) startStorage() {
+	if o.storage == nil {
+		return
+	}
+	o.saveCh = make(chan crawler.TaskResult, storageBuffer)
+	o.saveStop = make(chan struct{})
+	o.savedCh = make(chan struct{})
+	go func() {
+		defer close(o.savedCh)
+		for {
+			select {
+			case result := <-o.saveCh:
+				o.save(result)
+			case <-o.saveStop:
+				// save the pages still waiting before stopping
+				for {
+					select {
+					case result := <-o.saveCh:
+						o.save(result)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+// save saves a page, failing to save a page does not stop the crawl
+func (o *Orchestrator) save(result crawler.TaskResult) {
+	if err := o.storage.Save(result); err != nil {
+		o.logger.Error().Err(err).Str("url", result.URL.String()).Msg("failed to save page")
+	}
+}
+
+// stopStorage stops accepting pages and waits for the pages waiting to be saved, logging the pages lost
+// it is also called when the run loop didn't stop in time, so the pages are never sent on a closed channel
+func (o *Orchestrator) stopStorage() {
+	if o.saveCh == nil {
+		return
+	}
+	close(o.saveStop)
+	select {
+	case <-o.savedCh:
+	case <-time.After(storageTimeout):
+		o.logger.Warn().Dur("timeout", storageTimeout).Msg("storage not flushed in time")
+	}
+	if lost := atomic.LoadInt64(&o.unsaved) + int64(len(o.saveCh)); lost > 0 {
+		o.logger.Warn().Int64("lost", lost).Msg("pages not saved")
+	}
+}
+
+// keep stores a processed or failed page, in the storage if there's one or in memory otherwise
+func (o *Orchestrator) keep(pages map[string]crawler.TaskResult, result crawler.TaskResult) {
+	if o.saveCh == nil {
+		pages[result.URL.String()] = result
+		return
+	}
+	select {
+	case <-o.saveStop:
+		atomic.AddInt64(&o.unsaved, 1)
+		return
+	default:
+	}
+	select {
+	case o.saveCh <- result:
+	case <-o.saveStop:
+		atomic.AddInt64(&o.unsaved, 1)
+	}
+}
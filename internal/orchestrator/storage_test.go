@@ -0,0 +1,67 @@
+package orchestrator_test
+
+import (
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStorage keeps the saved pages
+type memoryStorage struct {
+	mu    sync.Mutex
+	pages map[string]crawler.TaskResult
+}
+
+func (s *memoryStorage) Save(result crawler.TaskResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages[result.URL.String()] = result
+	return nil
+}
+
+func TestOrchestrator_storage(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	storage := memoryStorage{pages: make(map[string]crawler.TaskResult)}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetStorage(&storage), orchestrator.SetMaxRetries(0))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop, the child fails
+	select {
+	case r := <-o.TaskQueue:
+		r.Tries++
+		o.DoneQueue <- crawler.TaskResult{Task: r, Children: map[string]int{"http://google.com/1": 1}}
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	select {
+	case r := <-o.TaskQueue:
+		r.Tries++
+		err := errors.New("timeout")
+		o.ErrorQueue <- crawler.TaskResult{Task: r, Error: &err}
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	select {
+	case <-o.Done():
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "crawl not finished")
+	}
+
+	// the pages are saved by the time the orchestrator is stopped, and they are not kept in memory
+	o.Stop()
+	require.Len(t, storage.pages, 2)
+	require.Equal(t, map[string]int{"http://google.com/1": 1}, storage.pages["http://google.com"].Children)
+	require.NotNil(t, storage.pages["http://google.com/1"].Error)
+	require.Empty(t, o.Processed)
+	require.Empty(t, o.Failed)
+	require.Equal(t, map[string]float64{"http://google.com": 0.5}, o.GetImportance())
+}
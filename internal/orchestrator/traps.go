@@ -0,0 +1,236 @@
+package orchestrator
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// heuristics that detect crawler traps
+const (
+	TrapURLLength     = "url-length"
+	TrapPathRepeats   = "path-repeats"
+	TrapQueryVariants = "query-variants"
+	TrapSimilarLinks  = "similar-links"
+)
+
+// Trap is an url pattern detected as a crawler trap
+type Trap struct {
+	// url pattern, numbers are replaced by * and ?* matches any query
+	Pattern string `json:"pattern"`
+	// heuristic that detected the trap
+	Reason string `json:"reason"`
+	// url that triggered the detection
+	URL string `json:"url"`
+	// number of links matching the pattern that were skipped
+	Throttled int `json:"throttled"`
+}
+
+// traps detects url patterns that generate endless unique urls, zero values disable a heuristic
+type traps struct {
+	maxURLLength     int
+	maxPathRepeats   int
+	maxQueryVariants int
+	maxSimilarLinks  int
+
+	// distinct queries seen for each path
+	queries map[string]map[string]struct{}
+	// patterns detected as traps
+	detected map[string]*Trap
+}
+
+// SetMaxURLLength sets the max length of the urls crawled
+func SetMaxURLLength(n int) Option {
+	return func(o *Orchestrator) {
+		o.traps.maxURLLength = n
+	}
+}
+
+// SetMaxPathRepeats sets the max number of times the same segment can appear in a path, such as /a/b/a/b/a/b
+func SetMaxPathRepeats(n int) Option {
+	return func(o *Orchestrator) {
+		o.traps.maxPathRepeats = n
+	}
+}
+
+// SetMaxQueryVariants sets the max number of distinct queries crawled for the same path, such as faceted searches
+func SetMaxQueryVariants(n int) Option {
+	return func(o *Orchestrator) {
+		o.traps.maxQueryVariants = n
+	}
+}
+
+// SetMaxSimilarLinks sets the max number of links in a page that only differ in their numbers, such as calendars
+// only links numbered in sequence are counted, so listings of unrelated ids are not detected as traps
+func SetMaxSimilarLinks(n int) Option {
+	return func(o *Orchestrator) {
+		o.traps.maxSimilarLinks = n
+	}
+}
+
+// Traps returns the crawler traps detected sorted by pattern
+func (o *Orchestrator) Traps() []Trap {
+	var result []Trap
+	for _, t := range o.traps.detected {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Pattern < result[j].Pattern
+	})
+	return result
+}
+
+// allowTrap checks if the url is a crawler trap
+// urls matching a pattern detected as a trap are throttled
+func (o *Orchestrator) allowTrap(u *url.URL) bool {
+	t := &o.traps
+	if t.throttle(u) {
+		o.logger.Debug().Str("url", u.String()).Msg("crawler trap detected, skipping task")
+		return false
+	}
+	switch {
+	case t.maxURLLength != 0 && len(u.String()) > t.maxURLLength:
+		o.detectTrap(urlPattern(u), TrapURLLength, u)
+		return false
+	case t.maxPathRepeats != 0 && pathRepeats(u.EscapedPath()) > t.maxPathRepeats:
+		o.detectTrap(urlPattern(u), TrapPathRepeats, u)
+		return false
+	}
+	if t.maxQueryVariants != 0 && u.RawQuery != "" {
+		path := u.Host + u.EscapedPath()
+		if t.queries == nil {
+			t.queries = make(map[string]map[string]struct{})
+		}
+		if t.queries[path] == nil {
+			t.queries[path] = make(map[string]struct{})
+		}
+		t.queries[path][u.RawQuery] = struct{}{}
+		if len(t.queries[path]) > t.maxQueryVariants {
+			o.detectTrap(queryPattern(u), TrapQueryVariants, u)
+			return false
+		}
+	}
+	return true
+}
+
+// detectSimilarLinks detects the links of a page that only differ in their numbers
+func (o *Orchestrator) detectSimilarLinks(children map[string]int) {
+	if o.traps.maxSimilarLinks == 0 {
+		return
+	}
+	var groups = make(map[string][]*url.URL)
+	for c := range children {
+		u, err := url.Parse(c)
+		if err != nil {
+			continue
+		}
+		p := urlPattern(u)
+		// links without numbers are never similar
+		if p == u.Host+u.EscapedPath()+querySuffix(u.RawQuery) {
+			continue
+		}
+		groups[p] = append(groups[p], u)
+	}
+	for p, links := range groups {
+		if len(links) > o.traps.maxSimilarLinks && sequence(links) > o.traps.maxSimilarLinks {
+			o.detectTrap(p, TrapSimilarLinks, links[0])
+		}
+	}
+}
+
+// detectTrap reports a pattern as a crawler trap
+func (o *Orchestrator) detectTrap(pattern, reason string, u *url.URL) {
+	t := &o.traps
+	if t.detected == nil {
+		t.detected = make(map[string]*Trap)
+	}
+	if trap, found := t.detected[pattern]; found {
+		trap.Throttled++
+		return
+	}
+	t.detected[pattern] = &Trap{Pattern: pattern, Reason: reason, URL: u.String(), Throttled: 1}
+	if reason == TrapSimilarLinks {
+		// the links of the page were not skipped yet
+		t.detected[pattern].Throttled = 0
+	}
+	o.logger.Warn().Str("pattern", pattern).Str("reason", reason).Str("url", u.String()).Msg("crawler trap detected")
+}
+
+// throttle checks if the url matches a pattern detected as a trap and counts it
+func (t *traps) throttle(u *url.URL) bool {
+	if t.detected == nil {
+		return false
+	}
+	for _, p := range []string{urlPattern(u), queryPattern(u)} {
+		if trap, found := t.detected[p]; found {
+			trap.Throttled++
+			return true
+		}
+	}
+	return false
+}
+
+// numbers matches the numbers in an url
+var numbers = regexp.MustCompile(`[0-9]+`)
+
+// sequence returns the length of the longest run of consecutive numbers ending the links, such as /2019/1, /2019/2
+func sequence(links []*url.URL) int {
+	var seen = make(map[int]struct{})
+	for _, u := range links {
+		found := numbers.FindAllString(u.EscapedPath()+querySuffix(u.RawQuery), -1)
+		if n, err := strconv.Atoi(found[len(found)-1]); err == nil {
+			seen[n] = struct{}{}
+		}
+	}
+	var longest int
+	for n := range seen {
+		// only count the runs from their first number
+		if _, found := seen[n-1]; found {
+			continue
+		}
+		length := 1
+		for _, found := seen[n+length]; found; _, found = seen[n+length] {
+			length++
+		}
+		if length > longest {
+			longest = length
+		}
+	}
+	return longest
+}
+
+// urlPattern returns the pattern of an url, with the numbers replaced by *
+func urlPattern(u *url.URL) string {
+	return u.Host + numbers.ReplaceAllString(u.EscapedPath()+querySuffix(u.RawQuery), "*")
+}
+
+// queryPattern returns the pattern of an url matching any query
+func queryPattern(u *url.URL) string {
+	return u.Host + numbers.ReplaceAllString(u.EscapedPath(), "*") + "?*"
+}
+
+// querySuffix returns the query with the leading ?, or an empty string if there's no query
+func querySuffix(q string) string {
+	if q == "" {
+		return ""
+	}
+	return "?" + q
+}
+
+// pathRepeats returns the number of times the most repeated segment appears in a path
+func pathRepeats(path string) int {
+	var max int
+	var count = make(map[string]int)
+	for _, s := range strings.Split(path, "/") {
+		if s == "" {
+			continue
+		}
+		count[s]++
+		if count[s] > max {
+			max = count[s]
+		}
+	}
+	return max
+}
@@ -0,0 +1,122 @@
+package orchestrator_test
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrchestrator_traps(t *testing.T) {
+	t.Run("path repeats", testOrchestrator_traps_repeats)
+	t.Run("similar links", testOrchestrator_traps_similar)
+	t.Run("similar links ids", testOrchestrator_traps_ids)
+}
+
+func testOrchestrator_traps_repeats(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxPathRepeats(2), orchestrator.SetMaxURLLength(40))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: map[string]int{
+		"http://google.com/a/b/a/b/a/b":                   1,
+		"http://google.com/session/aaaaaaaaaaaaaaaaaaaaa": 1,
+	}}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 1)
+	require.Equal(t, []orchestrator.Trap{
+		{Pattern: "google.com/a/b/a/b/a/b", Reason: orchestrator.TrapPathRepeats, URL: "http://google.com/a/b/a/b/a/b", Throttled: 1},
+		{Pattern: "google.com/session/aaaaaaaaaaaaaaaaaaaaa", Reason: orchestrator.TrapURLLength, URL: "http://google.com/session/aaaaaaaaaaaaaaaaaaaaa", Throttled: 1},
+	}, o.Traps())
+}
+
+func testOrchestrator_traps_similar(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxSimilarLinks(3))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1, the calendar links are throttled
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	children := map[string]int{"http://google.com/about": 1}
+	for i := 1; i <= 5; i++ {
+		children[fmt.Sprintf("http://google.com/calendar/2019/%d", i)] = 1
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: children}
+
+	// mock worker loop 2
+	host1, _ := url.Parse("http://google.com/about")
+	select {
+	case r := <-o.TaskQueue:
+		require.Equal(t, crawler.Task{URL: host1, Depth: 1, Tries: 0}, r)
+		o.DoneQueue <- crawler.TaskResult{Task: r}
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 2)
+	traps := o.Traps()
+	require.Len(t, traps, 1)
+	require.Equal(t, "google.com/calendar/*/*", traps[0].Pattern)
+	require.Equal(t, orchestrator.TrapSimilarLinks, traps[0].Reason)
+	require.Equal(t, 5, traps[0].Throttled)
+}
+
+func testOrchestrator_traps_ids(t *testing.T) {
+	host, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: host, Depth: 0, Tries: 1}
+
+	// start orchestrator
+	o := newTestOrchestrator(t, orchestrator.SetMaxSimilarLinks(3))
+	require.Nil(t, o.Start(host.String()))
+	defer o.Stop()
+
+	// mock worker loop 1, the product ids are not in sequence
+	select {
+	case <-o.TaskQueue:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "task not received")
+	}
+	children := make(map[string]int)
+	for _, id := range []int{17, 342, 9001, 55, 56} {
+		children[fmt.Sprintf("http://google.com/product/%d", id)] = 1
+	}
+	o.DoneQueue <- crawler.TaskResult{Task: task, Children: children}
+
+	// mock worker loop 2, all the products are crawled
+	for range children {
+		select {
+		case r := <-o.TaskQueue:
+			o.DoneQueue <- crawler.TaskResult{Task: r}
+		case <-time.After(1 * time.Second):
+			require.FailNow(t, "task not received")
+		}
+	}
+
+	<-o.Done()
+	require.Len(t, o.Processed, 6)
+	require.Empty(t, o.Traps())
+}
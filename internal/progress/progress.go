@@ -0,0 +1,211 @@
+package progress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"golang.org/x/term"
+)
+
+// progress formats
+const (
+	// Auto shows the text progress when the output is a terminal
+	Auto = "auto"
+	Off  = "off"
+	Text = "text"
+	JSON = "json"
+)
+
+// ErrInvalidFormat is returned when the progress format is not known
+var ErrInvalidFormat = errors.New("invalid progress, expected auto, off, text or json")
+
+// Event is a progress report of the crawl
+type Event struct {
+	Time      time.Time `json:"time"`
+	Elapsed   float64   `json:"elapsed_seconds"`
+	Processed int       `json:"processed"`
+	InProcess int       `json:"in_process"`
+	Queued    int       `json:"queued"`
+	Failed    int       `json:"failed"`
+	Retries   int       `json:"retries"`
+	Bytes     int64     `json:"bytes"`
+	// rates since the previous report, or since the start on the last report
+	PagesPerSecond float64 `json:"pages_per_second"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	// whether the crawl is finished, only set on the last report
+	Done bool `json:"done"`
+}
+
+// Reporter periodically reports the progress of a crawl
+type Reporter struct {
+	w      io.Writer
+	format string
+	stats  func() orchestrator.Stats
+
+	// interval between the reports
+	interval time.Duration
+	// ticks triggering the reports, instead of the interval
+	tick <-chan time.Time
+	// time the crawl started
+	start time.Time
+	// previous report and its time, to compute the rates
+	last     Event
+	lastTime time.Time
+
+	// gracefully shutdown reporter
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// Option is an optimal configuration option that can be applied to a reporter
+type Option func(r *Reporter)
+
+// ParseFormat validates a progress format, resolving auto to text if the file is a terminal and off otherwise
+func ParseFormat(format string, f *os.File) (string, error) {
+	switch format {
+	case Auto:
+		if IsTerminal(f) {
+			return Text, nil
+		}
+		return Off, nil
+	case Off, Text, JSON:
+		return format, nil
+	default:
+		return "", ErrInvalidFormat
+	}
+}
+
+// IsTerminal checks if the file is a terminal, unlike other character devices such as /dev/null
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// New instantiates a new reporter writing the progress returned by stats in the text or json format
+func New(w io.Writer, format string, stats func() orchestrator.Stats, opts ...Option) *Reporter {
+	r := Reporter{
+		w:        w,
+		format:   format,
+		stats:    stats,
+		interval: time.Second,
+	}
+	if format == Text {
+		r.interval = 200 * time.Millisecond
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return &r
+}
+
+// SetInterval sets the interval between the reports
+func SetInterval(d time.Duration) Option {
+	return func(r *Reporter) {
+		r.interval = d
+	}
+}
+
+// SetTick reports the progress on each tick of the channel instead of every interval
+func SetTick(tick <-chan time.Time) Option {
+	return func(r *Reporter) {
+		r.tick = tick
+	}
+}
+
+// Start starts reporting the progress
+func (r *Reporter) Start() {
+	r.start = time.Now()
+	r.last, r.lastTime = Event{}, r.start
+	r.stopCh = make(chan struct{})
+	r.doneCh = make(chan struct{})
+	go r.run()
+}
+
+// Stop writes the last report and stops reporting
+func (r *Reporter) Stop() {
+	if r.stopCh == nil {
+		return
+	}
+	close(r.stopCh)
+	<-r.doneCh
+}
+
+// run reports the progress every interval
+func (r *Reporter) run() {
+	defer close(r.doneCh)
+	tick := r.tick
+	if tick == nil {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+			r.report(false)
+		case <-r.stopCh:
+			r.report(true)
+			return
+		}
+	}
+}
+
+// report writes the current progress
+func (r *Reporter) report(done bool) {
+	s := r.stats()
+	now := time.Now()
+	e := Event{
+		Time:      now.UTC(),
+		Elapsed:   now.Sub(r.start).Seconds(),
+		Processed: s.Processed,
+		InProcess: s.InProcess,
+		Queued:    s.Queued,
+		Failed:    s.Failed,
+		Retries:   s.Retries,
+		Bytes:     s.Bytes,
+		Done:      done,
+	}
+	// the last report has the average rates of the whole crawl
+	since, previous := r.lastTime, r.last
+	if done {
+		since, previous = r.start, Event{}
+	}
+	if d := now.Sub(since).Seconds(); d > 0 {
+		e.PagesPerSecond = float64(e.Processed+e.Failed-previous.Processed-previous.Failed) / d
+		e.BytesPerSecond = float64(e.Bytes-previous.Bytes) / d
+	}
+	r.last, r.lastTime = e, now
+
+	if r.format == JSON {
+		_ = json.NewEncoder(r.w).Encode(e)
+		return
+	}
+	// the line is redrawn in place, the last one is kept
+	line := fmt.Sprintf("\r\033[K%d pages, %d in flight, %d queued, %d failed, %.1f pages/s, %s/s, %s",
+		e.Processed, e.InProcess, e.Queued, e.Failed, e.PagesPerSecond, bytes(e.BytesPerSecond), elapsed(now.Sub(r.start)))
+	if done {
+		line += "\n"
+	}
+	_, _ = io.WriteString(r.w, line)
+}
+
+// bytes formats a number of bytes
+func bytes(n float64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+// elapsed formats a duration as hh:mm:ss
+func elapsed(d time.Duration) string {
+	s := int(d.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
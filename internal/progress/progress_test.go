@@ -0,0 +1,79 @@
+package progress_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"github.com/pmdcosta/crawler/internal/progress"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	f, err := ioutil.TempFile("", "progress")
+	require.Nil(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// auto is only text on a terminal
+	format, err := progress.ParseFormat(progress.Auto, f)
+	require.Nil(t, err)
+	require.Equal(t, progress.Off, format)
+	format, err = progress.ParseFormat(progress.JSON, f)
+	require.Nil(t, err)
+	require.Equal(t, progress.JSON, format)
+	_, err = progress.ParseFormat("bar", f)
+	require.Equal(t, progress.ErrInvalidFormat, err)
+
+	// character devices other than terminals are not terminals
+	null, err := os.Open(os.DevNull)
+	require.Nil(t, err)
+	defer null.Close()
+	require.False(t, progress.IsTerminal(null))
+}
+
+func TestReporter_json(t *testing.T) {
+	var out bytes.Buffer
+	stats := orchestrator.Stats{Processed: 10, InProcess: 2, Queued: 5, Failed: 1, Bytes: 1024}
+	tick := make(chan time.Time)
+	r := progress.New(&out, progress.JSON, func() orchestrator.Stats { return stats }, progress.SetTick(tick))
+	r.Start()
+	tick <- time.Now()
+	tick <- time.Now()
+	r.Stop()
+
+	// an event is reported on every tick and once stopped
+	var events []progress.Event
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var e progress.Event
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &e))
+		events = append(events, e)
+	}
+	require.Len(t, events, 3)
+	last := events[len(events)-1]
+	require.True(t, last.Done)
+	require.Equal(t, 10, last.Processed)
+	require.Equal(t, 2, last.InProcess)
+	require.Equal(t, 5, last.Queued)
+	require.Equal(t, 1, last.Failed)
+	require.True(t, last.PagesPerSecond > 0)
+	require.False(t, events[0].Done)
+}
+
+func TestReporter_text(t *testing.T) {
+	var out bytes.Buffer
+	r := progress.New(&out, progress.Text, func() orchestrator.Stats {
+		return orchestrator.Stats{Processed: 3, InProcess: 1, Queued: 2, Bytes: 2048}
+	}, progress.SetInterval(time.Hour))
+	r.Start()
+	r.Stop()
+	require.True(t, strings.HasPrefix(out.String(), "\r\033[K3 pages, 1 in flight, 2 queued, 0 failed, "))
+	require.True(t, strings.HasSuffix(out.String(), "\n"))
+}
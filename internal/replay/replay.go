@@ -0,0 +1,199 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/warc"
+	"github.com/rs/zerolog"
+)
+
+// ErrNotRecorded is returned in strict mode when the page was not recorded
+var ErrNotRecorded = errors.New("page not recorded")
+
+// Replay is a backend that serves the pages of a previously recorded crawl
+type Replay struct {
+	logger *zerolog.Logger
+
+	// whether to fail the pages that were not recorded instead of returning a 404
+	strict bool
+
+	mu sync.RWMutex
+	// recorded responses by url
+	pages map[string]*crawler.Response
+}
+
+// Option is an optimal configuration option that can be applied to a replay
+type Option func(r *Replay)
+
+// fixture is a recorded response, the body is either inline or in a file with the same name and the .body extension
+// this is the same format used by the cache, so a cache directory can be replayed
+type fixture struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       *string     `json:"body,omitempty"`
+}
+
+// New instantiates a new replay without any recorded pages
+func New(logger *zerolog.Logger, opts ...Option) *Replay {
+	l := logger.With().Str("pkg", "replay").Logger()
+	r := Replay{
+		logger: &l,
+		pages:  make(map[string]*crawler.Response),
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return &r
+}
+
+// SetStrict fails the pages that were not recorded instead of returning a 404
+func SetStrict(strict bool) Option {
+	return func(r *Replay) {
+		r.strict = strict
+	}
+}
+
+// Load loads the recorded pages from a warc file or a fixture directory
+func (r *Replay) Load(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return r.LoadDir(path)
+	}
+	return r.LoadWARC(path)
+}
+
+// LoadWARC loads the responses recorded in a warc file
+// when a page was recorded multiple times, the last response is used
+func (r *Replay) LoadWARC(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader, err := warc.NewReader(f)
+	if err != nil {
+		return err
+	}
+	var n int
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		// only responses are replayed, revisit records refer to a response already loaded
+		if record.Type() != "response" {
+			continue
+		}
+		u, err := url.Parse(record.Header.Get("WARC-Target-URI"))
+		if err != nil {
+			return err
+		}
+		res, body, err := record.Response()
+		if err != nil {
+			return err
+		}
+		date, _ := time.Parse(time.RFC3339, record.Header.Get("WARC-Date"))
+		r.add(&crawler.Response{
+			URL:        u,
+			StatusCode: res.StatusCode,
+			Header:     res.Header,
+			Body:       body,
+			Size:       len(body),
+			Time:       date,
+		})
+		n++
+	}
+	r.logger.Info().Str("file", file).Int("pages", n).Msg("loaded warc file")
+	return nil
+}
+
+// LoadDir loads the responses recorded as json fixtures in a directory
+func (r *Replay) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var f fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return err
+		}
+		u, err := url.Parse(f.URL)
+		if err != nil {
+			return err
+		}
+		var body []byte
+		if f.Body != nil {
+			body = []byte(*f.Body)
+		} else if body, err = ioutil.ReadFile(strings.TrimSuffix(file, ".json") + ".body"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if f.StatusCode == 0 {
+			f.StatusCode = http.StatusOK
+		}
+		r.add(&crawler.Response{
+			URL:        u,
+			StatusCode: f.StatusCode,
+			Header:     f.Header,
+			Body:       body,
+			Size:       len(body),
+		})
+	}
+	r.logger.Info().Str("dir", dir).Int("pages", len(files)).Msg("loaded fixtures")
+	return nil
+}
+
+// add adds a recorded response
+func (r *Replay) add(res *crawler.Response) {
+	if res.Header == nil {
+		res.Header = make(http.Header)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pages[res.URL.String()] = res
+}
+
+// Do returns the recorded response of the page
+func (r *Replay) Do(ctx context.Context, u *url.URL) (*crawler.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	recorded, found := r.pages[u.String()]
+	r.mu.RUnlock()
+	if !found {
+		r.logger.Debug().Str("url", u.String()).Msg("page not recorded")
+		if r.strict {
+			return nil, ErrNotRecorded
+		}
+		return &crawler.Response{URL: u, StatusCode: http.StatusNotFound, Header: make(http.Header), Time: time.Now()}, nil
+	}
+
+	// return a copy so the recorded response is not modified
+	res := *recorded
+	res.Time = time.Now()
+	return &res, nil
+}
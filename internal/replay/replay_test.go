@@ -0,0 +1,85 @@
+package replay_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/replay"
+	"github.com/pmdcosta/crawler/internal/warc"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay_warc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := zerolog.Nop()
+
+	// record a page
+	u, _ := url.Parse("http://google.com")
+	w := warc.New(&logger, dir)
+	require.Nil(t, w.Write(u, &crawler.Response{
+		URL:        u,
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html"}},
+		Body:       []byte("body"),
+	}, nil))
+	require.Nil(t, w.Close())
+	files, _ := filepath.Glob(filepath.Join(dir, "*.warc.gz"))
+	require.Len(t, files, 1)
+
+	// replay the page
+	r := replay.New(&logger)
+	require.Nil(t, r.Load(files[0]))
+	res, err := r.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/html", res.Header.Get("Content-Type"))
+	require.Equal(t, "body", string(res.Body))
+
+	// pages not recorded are not found
+	missing, _ := url.Parse("http://google.com/missing")
+	res, err = r.Do(context.Background(), missing)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestReplay_dir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := zerolog.Nop()
+
+	// record the pages as fixtures
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "index.json"), []byte(`{"url":"http://google.com","body":"index"}`), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "gone.json"), []byte(`{"url":"http://google.com/gone","status":410,"header":{"X-Test":["1"]}}`), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "gone.body"), []byte("gone"), 0644))
+
+	// replay the pages
+	r := replay.New(&logger, replay.SetStrict(true))
+	require.Nil(t, r.Load(dir))
+	u, _ := url.Parse("http://google.com")
+	res, err := r.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "index", string(res.Body))
+
+	u, _ = url.Parse("http://google.com/gone")
+	res, err = r.Do(context.Background(), u)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusGone, res.StatusCode)
+	require.Equal(t, "1", res.Header.Get("X-Test"))
+	require.Equal(t, "gone", string(res.Body))
+
+	// strict mode fails the pages not recorded
+	u, _ = url.Parse("http://google.com/missing")
+	_, err = r.Do(context.Background(), u)
+	require.Equal(t, replay.ErrNotRecorded, err)
+}
@@ -0,0 +1,217 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidOverride is returned when an override is not in the host:port:addr format
+var ErrInvalidOverride = errors.New("invalid override, expected host:port:addr[,addr]")
+
+// Resolver resolves host names and caches the results
+// successful lookups are cached for the ttl and failed lookups for the negative ttl
+type Resolver struct {
+	// lookup resolves a host name into its addresses
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+	// how long the resolved addresses are cached
+	ttl time.Duration
+	// how long failed lookups are cached
+	negativeTTL time.Duration
+	// max time of a lookup, independent of the callers waiting for it
+	timeout time.Duration
+
+	// static addresses for host:port pairs, the port is empty for any port
+	overrides map[string][]string
+
+	mu    sync.Mutex
+	cache map[string]*entry
+	// last time the expired lookups were deleted
+	swept time.Time
+}
+
+// entry is a cached lookup
+type entry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+	// closed once the lookup is completed
+	ready chan struct{}
+}
+
+// Option is an optimal configuration option that can be applied to a resolver
+type Option func(r *Resolver)
+
+// New instantiates a new caching resolver
+func New(opts ...Option) *Resolver {
+	r := Resolver{
+		lookup:      net.DefaultResolver.LookupIPAddr,
+		ttl:         5 * time.Minute,
+		negativeTTL: 30 * time.Second,
+		timeout:     10 * time.Second,
+		overrides:   make(map[string][]string),
+		cache:       make(map[string]*entry),
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return &r
+}
+
+// SetTTL sets how long resolved addresses are cached, zero disables caching
+func SetTTL(t time.Duration) Option {
+	return func(r *Resolver) {
+		r.ttl = t
+	}
+}
+
+// SetNegativeTTL sets how long failed lookups are cached, zero disables caching
+func SetNegativeTTL(t time.Duration) Option {
+	return func(r *Resolver) {
+		r.negativeTTL = t
+	}
+}
+
+// SetTimeout sets the max time of a lookup
+func SetTimeout(t time.Duration) Option {
+	return func(r *Resolver) {
+		r.timeout = t
+	}
+}
+
+// SetNameserver sets the dns server used for the lookups instead of the system one
+func SetNameserver(addr string) Option {
+	return func(r *Resolver) {
+		resolver := net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+		r.lookup = resolver.LookupIPAddr
+	}
+}
+
+// AddOverride resolves a host to static addresses, similar to curl --resolve
+// if the port is empty or *, the override applies to any port
+func AddOverride(host, port string, addrs ...string) Option {
+	return func(r *Resolver) {
+		if port == "*" {
+			port = ""
+		}
+		r.overrides[net.JoinHostPort(strings.ToLower(host), port)] = addrs
+	}
+}
+
+// ParseOverride parses an override in the curl --resolve format host:port:addr[,addr]
+func ParseOverride(s string) (Option, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, ErrInvalidOverride
+	}
+	var addrs []string
+	for _, a := range strings.Split(parts[2], ",") {
+		a = strings.TrimSuffix(strings.TrimPrefix(a, "["), "]")
+		if net.ParseIP(a) == nil {
+			return nil, ErrInvalidOverride
+		}
+		addrs = append(addrs, a)
+	}
+	return AddOverride(parts[0], parts[1], addrs...), nil
+}
+
+// LookupHost returns the addresses of a host for connections to a port
+// failures are returned as *net.DNSError
+// the lookup is shared by all the callers and is not cancelled with the context, the caller only stops waiting for it
+func (r *Resolver) LookupHost(ctx context.Context, host, port string) ([]string, error) {
+	host = strings.ToLower(host)
+	if addrs, found := r.overrides[net.JoinHostPort(host, port)]; found {
+		return addrs, nil
+	}
+	if addrs, found := r.overrides[net.JoinHostPort(host, "")]; found {
+		return addrs, nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{host}, nil
+	}
+
+	// reuse a cached or in-flight lookup
+	r.mu.Lock()
+	r.sweep(time.Now())
+	e, found := r.cache[host]
+	if found {
+		select {
+		case <-e.ready:
+			if time.Now().After(e.expires) {
+				found = false
+			}
+		default:
+		}
+	}
+	if !found {
+		e = &entry{ready: make(chan struct{})}
+		r.cache[host] = e
+		r.mu.Unlock()
+		go r.resolve(host, e)
+	} else {
+		r.mu.Unlock()
+	}
+
+	select {
+	case <-e.ready:
+		return e.addrs, e.err
+	case <-ctx.Done():
+		return nil, &net.DNSError{Err: ctx.Err().Error(), Name: host, IsTimeout: true}
+	}
+}
+
+// sweep deletes the completed lookups that expired, at most once per ttl, so the cache doesn't grow with every host
+// crawled, the lock must be held
+func (r *Resolver) sweep(now time.Time) {
+	if now.Sub(r.swept) < r.ttl {
+		return
+	}
+	r.swept = now
+	for host, e := range r.cache {
+		select {
+		case <-e.ready:
+			if now.After(e.expires) {
+				delete(r.cache, host)
+			}
+		default:
+		}
+	}
+}
+
+// resolve looks up the host with its own timeout and completes the cache entry
+func (r *Resolver) resolve(host string, e *entry) {
+	defer close(e.ready)
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	ips, err := r.lookup(ctx, host)
+	if err == nil && len(ips) == 0 {
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	if err != nil {
+		// timeouts and temporary failures are not cached
+		cancelled := errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+		if _, ok := err.(*net.DNSError); !ok {
+			err = &net.DNSError{Err: err.Error(), Name: host, IsTimeout: cancelled}
+		}
+		e.err = err
+		e.expires = time.Now().Add(r.negativeTTL)
+		if dnsErr := err.(*net.DNSError); cancelled || dnsErr.IsTimeout || dnsErr.IsTemporary {
+			e.expires = time.Time{}
+		}
+		return
+	}
+	for _, ip := range ips {
+		e.addrs = append(e.addrs, ip.String())
+	}
+	e.expires = time.Now().Add(r.ttl)
+}
@@ -0,0 +1,133 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_cache(t *testing.T) {
+	var lookups int
+	r := New(SetTTL(time.Minute), SetNegativeTTL(time.Minute))
+	r.lookup = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		lookups++
+		if host == "missing.com" {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}}, nil
+	}
+
+	// resolved hosts are cached
+	for i := 0; i < 2; i++ {
+		addrs, err := r.LookupHost(context.Background(), "Google.com", "80")
+		require.Nil(t, err)
+		require.Equal(t, []string{"10.0.0.1"}, addrs)
+	}
+	require.Equal(t, 1, lookups)
+
+	// failed lookups are cached
+	for i := 0; i < 2; i++ {
+		_, err := r.LookupHost(context.Background(), "missing.com", "80")
+		require.IsType(t, &net.DNSError{}, err)
+	}
+	require.Equal(t, 2, lookups)
+
+	// expired entries are resolved again
+	r.cache["google.com"].expires = time.Now()
+	_, err := r.LookupHost(context.Background(), "google.com", "80")
+	require.Nil(t, err)
+	require.Equal(t, 3, lookups)
+}
+
+func TestResolver_sweep(t *testing.T) {
+	r := New(SetTTL(time.Minute))
+	r.lookup = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}}, nil
+	}
+	for _, host := range []string{"a.google.com", "b.google.com", "c.google.com"} {
+		_, err := r.LookupHost(context.Background(), host, "80")
+		require.Nil(t, err)
+	}
+	require.Len(t, r.cache, 3)
+
+	// the expired entries are only deleted once per ttl
+	r.cache["a.google.com"].expires = time.Now()
+	r.cache["b.google.com"].expires = time.Now()
+	_, err := r.LookupHost(context.Background(), "d.google.com", "80")
+	require.Nil(t, err)
+	require.Len(t, r.cache, 4)
+
+	// the expired entries of other hosts are deleted on a later lookup
+	r.swept = time.Now().Add(-time.Minute)
+	_, err = r.LookupHost(context.Background(), "c.google.com", "80")
+	require.Nil(t, err)
+	require.Len(t, r.cache, 2)
+	require.Contains(t, r.cache, "c.google.com")
+	require.Contains(t, r.cache, "d.google.com")
+}
+
+func TestResolver_cancel(t *testing.T) {
+	var lookups int
+	release := make(chan struct{})
+	r := New(SetNegativeTTL(time.Minute), SetTimeout(50*time.Millisecond))
+	r.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups++
+		select {
+		case <-release:
+			return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// a cancelled caller stops waiting without failing the lookup
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := r.LookupHost(ctx, "google.com", "80")
+	require.IsType(t, &net.DNSError{}, err)
+	close(release)
+	addrs, err := r.LookupHost(context.Background(), "google.com", "80")
+	require.Nil(t, err)
+	require.Equal(t, []string{"10.0.0.1"}, addrs)
+	require.Equal(t, 1, lookups)
+
+	// lookups timing out are not cached
+	r.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups++
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	for i := 0; i < 2; i++ {
+		_, err = r.LookupHost(context.Background(), "slow.com", "80")
+		require.True(t, err.(*net.DNSError).IsTimeout)
+	}
+	require.Equal(t, 3, lookups)
+}
+
+func TestResolver_override(t *testing.T) {
+	opt, err := ParseOverride("google.com:443:127.0.0.1,[::1]")
+	require.Nil(t, err)
+	r := New(opt, AddOverride("docs.google.com", "*", "127.0.0.2"))
+	r.lookup = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}}, nil
+	}
+
+	addrs, err := r.LookupHost(context.Background(), "google.com", "443")
+	require.Nil(t, err)
+	require.Equal(t, []string{"127.0.0.1", "::1"}, addrs)
+
+	// overrides only apply to their port
+	addrs, err = r.LookupHost(context.Background(), "google.com", "80")
+	require.Nil(t, err)
+	require.Equal(t, []string{"10.0.0.1"}, addrs)
+
+	addrs, err = r.LookupHost(context.Background(), "docs.google.com", "80")
+	require.Nil(t, err)
+	require.Equal(t, []string{"127.0.0.2"}, addrs)
+
+	_, err = ParseOverride("google.com:443")
+	require.Equal(t, ErrInvalidOverride, err)
+}
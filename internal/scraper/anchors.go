@@ -0,0 +1,52 @@
+package scraper
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ScrapeAnchors returns the distinct anchor texts of each link in a html page
+// links without text use the alt text of their images or their title
+func ScrapeAnchors(root *url.URL, page []byte) map[string][]string {
+	// load the HTML document
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return nil
+	}
+	return scrapeAnchors(root, doc)
+}
+
+// scrapeAnchors returns the distinct anchor texts of each link in a parsed html page
+func scrapeAnchors(root *url.URL, doc *goquery.Document) map[string][]string {
+	var anchors = make(map[string][]string)
+	doc.Find(baseHref).Each(func(_ int, sel *goquery.Selection) {
+		href, _ := sel.Attr(attrHref)
+		u := processHref(root, href)
+		if u == nil {
+			return
+		}
+		text := anchorText(sel)
+		for _, t := range anchors[u.String()] {
+			if t == text {
+				return
+			}
+		}
+		anchors[u.String()] = append(anchors[u.String()], text)
+	})
+	return anchors
+}
+
+// anchorText returns the text of a link
+func anchorText(sel *goquery.Selection) string {
+	text := strings.Join(strings.Fields(sel.Text()), " ")
+	if text == "" {
+		text, _ = sel.Find("img[alt]").First().Attr("alt")
+	}
+	if text == "" {
+		text, _ = sel.Attr("title")
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,35 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pmdcosta/crawler/internal/crawler"
+)
+
+// AddDetails returns a post-processor that sets the title of the page and the anchor texts of its links in the task result
+// the page is parsed once for both, and the details not requested are not extracted
+func AddDetails(title, anchors bool) func(ctx context.Context, result *crawler.TaskResult) error {
+	return func(ctx context.Context, result *crawler.TaskResult) error {
+		if result.Response == nil || (!title && !anchors) {
+			return nil
+		}
+		// load the HTML document
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(result.Response.Body))
+		if err != nil {
+			return nil
+		}
+		if title {
+			result.Title = scrapeTitle(doc)
+		}
+		if anchors {
+			root := result.URL
+			if result.Response.URL != nil {
+				root = result.Response.URL
+			}
+			result.Anchors = scrapeAnchors(root, doc)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	baseForm     = "form"
+	passwordForm = "form:has(input[type=password])"
+	formInputs   = "input[name], textarea[name], select[name]"
+)
+
+// ErrFormNotFound is returned when the page has no form matching the selector
+var ErrFormNotFound = errors.New("form not found")
+
+// Form is a html form ready to be submitted
+type Form struct {
+	// url the form is submitted to
+	Action *url.URL
+	// http method used to submit the form
+	Method string
+	// values of all the form fields, including hidden fields
+	Values url.Values
+}
+
+// ScrapeForm returns the form matching the selector in a html page
+// if no selector is provided, the first form with a password field is used, or the first form in the page
+func ScrapeForm(root *url.URL, page []byte, selector string) (*Form, error) {
+	// load the HTML document
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return nil, err
+	}
+
+	// find the form
+	var sel *goquery.Selection
+	if selector != "" {
+		sel = doc.Find(selector).First()
+	} else if sel = doc.Find(passwordForm).First(); sel.Length() == 0 {
+		sel = doc.Find(baseForm).First()
+	}
+	if sel.Length() == 0 {
+		return nil, ErrFormNotFound
+	}
+	return scrapeForm(root, sel)
+}
+
+// scrapeForm returns the action, method and default values of a html form
+func scrapeForm(root *url.URL, sel *goquery.Selection) (*Form, error) {
+	form := Form{
+		Action: root,
+		Method: http.MethodGet,
+		Values: make(url.Values),
+	}
+	if action, exists := sel.Attr("action"); exists && action != "" {
+		u, err := root.Parse(action)
+		if err != nil {
+			return nil, err
+		}
+		form.Action = u
+	}
+	if method, exists := sel.Attr("method"); exists && strings.EqualFold(method, http.MethodPost) {
+		form.Method = http.MethodPost
+	}
+
+	// collect the default value of each field, this includes hidden csrf tokens
+	sel.Find(formInputs).Each(func(_ int, input *goquery.Selection) {
+		name, _ := input.Attr("name")
+		switch goquery.NodeName(input) {
+		case "textarea":
+			form.Values.Add(name, input.Text())
+		case "select":
+			option := input.Find("option[selected]").First()
+			if option.Length() == 0 {
+				option = input.Find("option").First()
+			}
+			if value, exists := option.Attr("value"); exists {
+				form.Values.Add(name, value)
+			} else if option.Length() != 0 {
+				form.Values.Add(name, option.Text())
+			}
+		default:
+			value, _ := input.Attr("value")
+			switch strings.ToLower(input.AttrOr("type", "text")) {
+			case "submit", "button", "image", "reset", "file":
+				return
+			case "checkbox", "radio":
+				if _, checked := input.Attr("checked"); !checked {
+					return
+				}
+				if value == "" {
+					value = "on"
+				}
+			}
+			form.Values.Add(name, value)
+		}
+	})
+	return &form, nil
+}
@@ -0,0 +1,53 @@
+package scraper_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/scraper"
+	"github.com/stretchr/testify/require"
+)
+
+var formPage = []byte(`<html><body>
+<form action="/search"><input name="q" value="go"></form>
+<form id="login" action="/session" method="post">
+	<input type="hidden" name="csrf" value="token">
+	<input name="user">
+	<input type="password" name="pass">
+	<input type="checkbox" name="remember" checked>
+	<input type="checkbox" name="newsletter" value="yes">
+	<select name="lang"><option value="en">English</option><option value="pt" selected>Portuguese</option></select>
+	<textarea name="note">hello</textarea>
+	<input type="submit" name="go" value="Login">
+</form>
+</body></html>`)
+
+func TestScrapeForm(t *testing.T) {
+	root, _ := url.Parse("http://google.com/login")
+
+	// the form with a password field is used by default
+	form, err := scraper.ScrapeForm(root, formPage, "")
+	require.Nil(t, err)
+	require.Equal(t, "http://google.com/session", form.Action.String())
+	require.Equal(t, http.MethodPost, form.Method)
+	require.Equal(t, url.Values{
+		"csrf":     {"token"},
+		"user":     {""},
+		"pass":     {""},
+		"remember": {"on"},
+		"lang":     {"pt"},
+		"note":     {"hello"},
+	}, form.Values)
+
+	// the form matching the selector
+	form, err = scraper.ScrapeForm(root, formPage, "form[action='/search']")
+	require.Nil(t, err)
+	require.Equal(t, "http://google.com/search", form.Action.String())
+	require.Equal(t, http.MethodGet, form.Method)
+	require.Equal(t, url.Values{"q": {"go"}}, form.Values)
+
+	// no form
+	_, err = scraper.ScrapeForm(root, []byte("<html></html>"), "")
+	require.Equal(t, scraper.ErrFormNotFound, err)
+}
@@ -2,6 +2,7 @@ package scraper
 
 import (
 	"bytes"
+	"context"
 	"net/url"
 	"strings"
 
@@ -14,7 +15,11 @@ const (
 )
 
 // ScrapePage returns all the links in a html page and the number of hits for each link
-func ScrapePage(root *url.URL, page []byte) map[string]int {
+// nothing is scraped once the context is cancelled
+func ScrapePage(ctx context.Context, root *url.URL, page []byte) map[string]int {
+	if ctx.Err() != nil {
+		return nil
+	}
 	// load the HTML document
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
 	if err != nil {
@@ -1,9 +1,11 @@
 package scraper_test
 
 import (
+	"context"
 	"net/url"
 	"testing"
 
+	"github.com/pmdcosta/crawler/internal/crawler"
 	"github.com/pmdcosta/crawler/internal/scraper"
 	"github.com/stretchr/testify/assert"
 )
@@ -44,6 +46,47 @@ var expected = map[string]int{
 
 func TestScrapePage(t *testing.T) {
 	host, _ := url.Parse("http://google.com")
-	urls := scraper.ScrapePage(host, body)
+	urls := scraper.ScrapePage(context.Background(), host, body)
 	assert.Equal(t, expected, urls)
 }
+
+func TestScraper_title(t *testing.T) {
+	assert.Equal(t, "Google", scraper.ScrapeTitle(body))
+	assert.Equal(t, "Hello World", scraper.ScrapeTitle([]byte("<html><head><title>\n  Hello\n  World </title></head></html>")))
+	assert.Equal(t, "", scraper.ScrapeTitle([]byte("<html><body>no title</body></html>")))
+}
+
+func TestScraper_anchors(t *testing.T) {
+	root, _ := url.Parse("http://google.com/docs/")
+	page := []byte(`<html><body>
+<a href="a">First
+  link</a>
+<a href="a">First link</a>
+<a href="/a">Other text</a>
+<a href="b"><img src="b.png" alt="Image"></a>
+<a href="c" title="Title"></a>
+<a href="#top">Top</a>
+</body></html>`)
+	assert.Equal(t, map[string][]string{
+		"http://google.com/docs/a": {"First link"},
+		"http://google.com/a":      {"Other text"},
+		"http://google.com/docs/b": {"Image"},
+		"http://google.com/docs/c": {"Title"},
+	}, scraper.ScrapeAnchors(root, page))
+}
+
+func TestScraper_details(t *testing.T) {
+	u, _ := url.Parse("http://google.com")
+	page := []byte(`<html><head><title>Home</title></head><body><a href="/a">First</a></body></html>`)
+
+	// only the requested details are set
+	result := crawler.TaskResult{Task: crawler.Task{URL: u}, Response: &crawler.Response{Body: page}}
+	assert.Nil(t, scraper.AddDetails(true, false)(context.Background(), &result))
+	assert.Equal(t, "Home", result.Title)
+	assert.Nil(t, result.Anchors)
+
+	result = crawler.TaskResult{Task: crawler.Task{URL: u}, Response: &crawler.Response{Body: page}}
+	assert.Nil(t, scraper.AddDetails(true, true)(context.Background(), &result))
+	assert.Equal(t, "Home", result.Title)
+	assert.Equal(t, map[string][]string{"http://google.com/a": {"First"}}, result.Anchors)
+}
@@ -0,0 +1,25 @@
+package scraper
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const baseTitle = "title"
+
+// ScrapeTitle returns the title of a html page
+func ScrapeTitle(page []byte) string {
+	// load the HTML document
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return ""
+	}
+	return scrapeTitle(doc)
+}
+
+// scrapeTitle returns the title of a parsed html page
+func scrapeTitle(doc *goquery.Document) string {
+	return strings.Join(strings.Fields(doc.Find(baseTitle).First().Text()), " ")
+}
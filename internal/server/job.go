@@ -0,0 +1,178 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"github.com/pmdcosta/crawler/internal/sink"
+)
+
+// statuses of a job
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusFinished  = "finished"
+	StatusCancelled = "cancelled"
+)
+
+// ErrInvalidSeed is returned when a job has no seeds or a seed is not an http url
+var ErrInvalidSeed = errors.New("invalid seeds, expected at least one http or https url")
+
+// JobRequest is the configuration of a crawl job, the fields omitted keep their defaults
+type JobRequest struct {
+	// hosts the crawl starts from
+	Seeds []string `json:"seeds"`
+	// max depth of the tree, 0 is unlimited when the server allows it
+	Depth int `json:"depth"`
+	// number of concurrent requests
+	Parallelism int `json:"parallelism"`
+	// max retry count for each failed page
+	Retries int `json:"retries"`
+	// crawl other hosts than the seeds
+	AllHosts bool `json:"all_hosts"`
+	// only crawl these exact hosts
+	Hosts []string `json:"hosts"`
+	// only crawl these domains and their subdomains
+	Domains []string `json:"domains"`
+}
+
+// defaultRequest returns a request with the default configuration
+func defaultRequest() JobRequest {
+	return JobRequest{Depth: 1, Parallelism: 10, Retries: 3}
+}
+
+// validate checks the request can be crawled within the max parallelism and depth, 0 being unlimited
+func (r JobRequest) validate(maxParallelism, maxDepth int) error {
+	if len(r.Seeds) == 0 {
+		return ErrInvalidSeed
+	}
+	for _, s := range r.Seeds {
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidSeed
+		}
+	}
+	if r.Parallelism <= 0 {
+		return errors.New("invalid parallelism, expected at least 1")
+	}
+	if r.Depth < 0 || r.Retries < 0 {
+		return errors.New("invalid depth or retries, expected 0 or more")
+	}
+	if maxParallelism > 0 && r.Parallelism > maxParallelism {
+		return fmt.Errorf("invalid parallelism, expected at most %d", maxParallelism)
+	}
+	if maxDepth > 0 && (r.Depth == 0 || r.Depth > maxDepth) {
+		return fmt.Errorf("invalid depth, expected between 1 and %d", maxDepth)
+	}
+	return nil
+}
+
+// Job is the state of a crawl job
+type Job struct {
+	ID       string     `json:"id"`
+	Request  JobRequest `json:"request"`
+	Status   string     `json:"status"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Progress Progress   `json:"progress"`
+}
+
+// Progress of a crawl job
+type Progress struct {
+	// pages crawled
+	Processed int `json:"processed"`
+	// pages that failed to be crawled
+	Failed int `json:"failed"`
+	// bytes downloaded
+	Bytes int64 `json:"bytes"`
+}
+
+// job is a crawl job managed by the server
+type job struct {
+	mu sync.Mutex
+	Job
+
+	// summary of the crawl once the job is done
+	summary *sink.Summary
+
+	// cancel the job
+	cancel     chan struct{}
+	cancelOnce sync.Once
+	// closed once the job is done
+	done chan struct{}
+}
+
+// newJob creates a queued job
+func newJob(id string, req JobRequest) *job {
+	return &job{
+		Job:    Job{ID: id, Request: req, Status: StatusQueued, Created: time.Now().UTC()},
+		cancel: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Observe counts the crawled pages, the job observes its orchestrator
+func (j *job) Observe(e orchestrator.Event) {
+	var result crawler.TaskResult
+	switch e := e.(type) {
+	case orchestrator.TaskSucceeded:
+		result = e.Result
+	case orchestrator.TaskFailed:
+		result = e.Result
+	default:
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if result.Error != nil && *result.Error != nil {
+		j.Progress.Failed++
+	} else {
+		j.Progress.Processed++
+	}
+	if result.Response != nil {
+		j.Progress.Bytes += int64(result.Response.Size)
+	}
+}
+
+// snapshot returns a copy of the state of the job
+func (j *job) snapshot() Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.Job
+}
+
+// results returns the summary of the crawl, nil until the job is done
+func (j *job) results() *sink.Summary {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.summary
+}
+
+// start marks the job as running
+func (j *job) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now().UTC()
+	j.Status, j.Started = StatusRunning, &now
+}
+
+// finish marks the job as done with the summary of the crawl
+func (j *job) finish(status string, summary *sink.Summary) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now().UTC()
+	j.Status, j.Finished, j.summary = status, &now, summary
+}
+
+// stop cancels the job, it can be called multiple times
+func (j *job) stop() {
+	j.cancelOnce.Do(func() {
+		close(j.cancel)
+	})
+}
@@ -0,0 +1,406 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/backend"
+	"github.com/pmdcosta/crawler/internal/export"
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+	"github.com/pmdcosta/crawler/internal/scraper"
+	"github.com/pmdcosta/crawler/internal/sink"
+	"github.com/pmdcosta/crawler/internal/worker"
+	"github.com/rs/zerolog"
+)
+
+// contentTypes are the content types of the output formats
+var contentTypes = map[string]string{
+	sink.Raw:        "text/plain; charset=utf-8",
+	sink.JSON:       "application/json",
+	sink.NDJSON:     "application/x-ndjson",
+	sink.Importance: "application/json",
+	sink.GraphML:    "application/xml",
+	sink.GEXF:       "application/xml",
+	sink.DOT:        "text/vnd.graphviz",
+	sink.CSV:        "text/csv",
+	sink.TSV:        "text/tab-separated-values",
+	sink.HTML:       "text/html; charset=utf-8",
+	sink.JUnit:      "application/xml",
+	sink.SARIF:      "application/sarif+json",
+	sink.Crawl:      "application/json",
+}
+
+// Server is a http api to submit and monitor crawl jobs
+type Server struct {
+	logger *zerolog.Logger
+	// logger the jobs log to, tagged with the job id
+	jobLogger *zerolog.Logger
+
+	// jobs submitted by id
+	mu     sync.Mutex
+	jobs   map[string]*job
+	lastID int
+
+	// a slot is taken by each running job
+	slots chan struct{}
+	// interval between the progress events
+	interval time.Duration
+	// max parallelism and depth a job can request, 0 is unlimited
+	maxParallelism int
+	maxDepth       int
+	// how long the finished jobs are kept before they are evicted, 0 keeps them forever
+	retention time.Duration
+	// creates the backend of each job
+	backend func(logger *zerolog.Logger) worker.Backend
+}
+
+// Option is an optimal configuration option that can be applied to a server
+type Option func(s *Server)
+
+// New instantiates a new server
+func New(logger *zerolog.Logger, opts ...Option) *Server {
+	l := logger.With().Str("pkg", "server").Logger()
+	s := Server{
+		logger:    &l,
+		jobLogger: logger,
+		jobs:      make(map[string]*job),
+		slots:     make(chan struct{}, 2),
+		interval:  time.Second,
+
+		maxParallelism: 50,
+		maxDepth:       10,
+		retention:      time.Hour,
+		backend: func(logger *zerolog.Logger) worker.Backend {
+			return backend.New(logger)
+		},
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return &s
+}
+
+// SetMaxJobs sets the max number of jobs running at the same time, the others wait in the queue
+func SetMaxJobs(n int) Option {
+	return func(s *Server) {
+		s.slots = make(chan struct{}, n)
+	}
+}
+
+// SetInterval sets the interval between the progress events
+func SetInterval(d time.Duration) Option {
+	return func(s *Server) {
+		s.interval = d
+	}
+}
+
+// SetMaxParallelism sets the max number of concurrent requests of a job, 0 is unlimited
+func SetMaxParallelism(n int) Option {
+	return func(s *Server) {
+		s.maxParallelism = n
+	}
+}
+
+// SetMaxDepth sets the max depth of a job, 0 is unlimited and allows jobs without a depth
+func SetMaxDepth(n int) Option {
+	return func(s *Server) {
+		s.maxDepth = n
+	}
+}
+
+// SetRetention sets how long the finished jobs are kept, 0 keeps them until they are deleted
+func SetRetention(d time.Duration) Option {
+	return func(s *Server) {
+		s.retention = d
+	}
+}
+
+// SetBackend sets the function creating the backend of each job
+func SetBackend(f func(logger *zerolog.Logger) worker.Backend) Option {
+	return func(s *Server) {
+		s.backend = f
+	}
+}
+
+// Close cancels all the jobs and waits for them to stop
+func (s *Server) Close() {
+	s.mu.Lock()
+	var jobs []*job
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mu.Unlock()
+	for _, j := range jobs {
+		j.stop()
+	}
+	for _, j := range jobs {
+		<-j.done
+	}
+}
+
+// ServeHTTP routes the requests
+//
+//	POST /jobs                  submits a job
+//	GET  /jobs                  lists the jobs
+//	GET  /jobs/{id}             returns a job
+//	DELETE /jobs/{id}           deletes a finished or cancelled job
+//	POST /jobs/{id}/cancel      cancels a job
+//	GET  /jobs/{id}/events      streams the progress of a job as server-sent events
+//	GET  /jobs/{id}/results     downloads the results of a job, in the format of the format query parameter
+func (s *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if parts[0] != "jobs" || len(parts) > 3 {
+		writeError(res, http.StatusNotFound, "not found")
+		return
+	}
+	if len(parts) == 1 {
+		switch req.Method {
+		case http.MethodPost:
+			s.submit(res, req)
+		case http.MethodGet:
+			s.list(res)
+		default:
+			writeError(res, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	s.mu.Lock()
+	j, found := s.jobs[parts[1]]
+	s.mu.Unlock()
+	if !found {
+		writeError(res, http.StatusNotFound, "job not found")
+		return
+	}
+	action := ""
+	if len(parts) == 3 {
+		action = parts[2]
+	}
+	switch {
+	case action == "" && req.Method == http.MethodGet:
+		writeJSON(res, http.StatusOK, j.snapshot())
+	case action == "" && req.Method == http.MethodDelete:
+		s.delete(res, j)
+	case action == "cancel" && req.Method == http.MethodPost:
+		j.stop()
+		writeJSON(res, http.StatusAccepted, j.snapshot())
+	case action == "events" && req.Method == http.MethodGet:
+		s.events(res, req, j)
+	case action == "results" && req.Method == http.MethodGet:
+		s.results(res, req, j)
+	case action == "" || action == "cancel" || action == "events" || action == "results":
+		writeError(res, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeError(res, http.StatusNotFound, "not found")
+	}
+}
+
+// submit queues a new job
+func (s *Server) submit(res http.ResponseWriter, req *http.Request) {
+	r := defaultRequest()
+	if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+		writeError(res, http.StatusBadRequest, "invalid job: "+err.Error())
+		return
+	}
+	if err := r.validate(s.maxParallelism, s.maxDepth); err != nil {
+		writeError(res, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.evict()
+	s.lastID++
+	j := newJob(strconv.Itoa(s.lastID), r)
+	s.jobs[j.ID] = j
+	s.mu.Unlock()
+	s.logger.Info().Str("job", j.ID).Strs("seeds", r.Seeds).Msg("job submitted")
+	go s.run(j)
+	writeJSON(res, http.StatusCreated, j.snapshot())
+}
+
+// delete removes a job once it is done, its results are no longer available
+func (s *Server) delete(res http.ResponseWriter, j *job) {
+	if j.results() == nil {
+		writeError(res, http.StatusConflict, "job is not done")
+		return
+	}
+	s.mu.Lock()
+	delete(s.jobs, j.ID)
+	s.mu.Unlock()
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// evict removes the jobs finished for longer than the retention, the lock must be held
+func (s *Server) evict() {
+	if s.retention == 0 {
+		return
+	}
+	for id, j := range s.jobs {
+		if finished := j.snapshot().Finished; finished != nil && time.Since(*finished) > s.retention {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// list returns all the jobs in the order they were submitted
+func (s *Server) list(res http.ResponseWriter) {
+	s.mu.Lock()
+	s.evict()
+	var jobs = []Job{}
+	for _, j := range s.jobs {
+		jobs = append(jobs, j.snapshot())
+	}
+	s.mu.Unlock()
+	sort.Slice(jobs, func(i, j int) bool {
+		a, _ := strconv.Atoi(jobs[i].ID)
+		b, _ := strconv.Atoi(jobs[j].ID)
+		return a < b
+	})
+	writeJSON(res, http.StatusOK, jobs)
+}
+
+// events streams the state of the job until it is done or the client goes away
+func (s *Server) events(res http.ResponseWriter, req *http.Request, j *job) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		writeError(res, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		snapshot := j.snapshot()
+		data, _ := json.Marshal(snapshot)
+		fmt.Fprintf(res, "event: progress\ndata: %s\n\n", data)
+		flusher.Flush()
+		if snapshot.Finished != nil {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-j.done:
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// results writes the results of a finished or cancelled job
+func (s *Server) results(res http.ResponseWriter, req *http.Request, j *job) {
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = sink.JSON
+	}
+	contentType, found := contentTypes[format]
+	if !found {
+		writeError(res, http.StatusBadRequest, sink.ErrUnknownOutput.Error())
+		return
+	}
+	summary := j.results()
+	if summary == nil {
+		writeError(res, http.StatusConflict, "job is not done")
+		return
+	}
+	res.Header().Set("Content-Type", contentType)
+	if err := sink.WriteSummary(res, format, summary); err != nil {
+		s.logger.Error().Err(err).Str("job", j.ID).Str("format", format).Msg("failed to write results")
+	}
+}
+
+// run crawls a job with its own orchestrator and workers, once there's a free slot
+func (s *Server) run(j *job) {
+	defer close(j.done)
+	select {
+	case s.slots <- struct{}{}:
+	case <-j.cancel:
+		j.finish(StatusCancelled, &sink.Summary{Seed: j.Request.Seeds[0]})
+		return
+	}
+	defer func() { <-s.slots }()
+	j.start()
+
+	l := s.jobLogger.With().Str("job", j.ID).Logger()
+	r := j.Request
+	options := []orchestrator.Option{
+		orchestrator.SetMaxRetries(r.Retries),
+		orchestrator.AddObserver(j),
+	}
+	if r.Depth != 0 {
+		options = append(options, orchestrator.SetMaxDepth(r.Depth))
+	}
+	for _, seed := range r.Seeds[1:] {
+		options = append(options, orchestrator.AddSeed(seed))
+	}
+	if !r.AllHosts {
+		for _, seed := range r.Seeds {
+			u, _ := url.Parse(seed)
+			options = append(options, orchestrator.AddSudDomainFilters(u.Host))
+		}
+	}
+	for _, h := range r.Hosts {
+		options = append(options, orchestrator.AddExactHostFilter(h))
+	}
+	for _, d := range r.Domains {
+		options = append(options, orchestrator.AddSudDomainFilters(d))
+	}
+
+	o := orchestrator.New(&l, r.Parallelism, options...)
+	b := s.backend(&l)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var workers []*worker.Worker
+	for i := 0; i < r.Parallelism; i++ {
+		w := worker.New(&l, o.TaskQueue, o.DoneQueue, o.ErrorQueue, b, scraper.ScrapePage, worker.SetContext(ctx), worker.AddPostProcessor(scraper.AddDetails(true, false)))
+		_ = w.Start()
+		workers = append(workers, w)
+	}
+	_ = o.Start(r.Seeds[0])
+
+	status := StatusFinished
+	select {
+	case <-o.Done():
+	case <-j.cancel:
+		status = StatusCancelled
+	}
+	// cancelling aborts the requests of all the workers at once
+	cancel()
+	for _, w := range workers {
+		w.Stop()
+	}
+	o.Stop()
+
+	pages := export.Pages(o.Processed, o.Failed)
+	j.finish(status, &sink.Summary{
+		Seed:       r.Seeds[0],
+		Processed:  o.Processed,
+		Failed:     o.Failed,
+		Pages:      pages,
+		Importance: o.GetImportance(),
+		Findings:   export.Check(pages, 0),
+	})
+	s.logger.Info().Str("job", j.ID).Str("status", status).Int("hits", len(o.Processed)).Msg("job done")
+}
+
+// writeJSON writes a json response
+func writeJSON(res http.ResponseWriter, status int, v interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	_ = json.NewEncoder(res).Encode(v)
+}
+
+// writeError writes a json error response
+func writeError(res http.ResponseWriter, status int, message string) {
+	writeJSON(res, status, map[string]string{"error": message})
+}
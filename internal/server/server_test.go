@@ -0,0 +1,172 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/server"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	// generate a test site with two pages
+	site := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/" {
+			_, _ = res.Write([]byte(`<html><head><title>Home</title></head><body><a href="/about">about</a></body></html>`))
+			return
+		}
+		_, _ = res.Write([]byte(`<html><body><a href="/">home</a></body></html>`))
+	}))
+	defer site.Close()
+
+	logger := zerolog.Nop()
+	s := server.New(&logger, server.SetInterval(10*time.Millisecond))
+	defer s.Close()
+	api := httptest.NewServer(s)
+	defer api.Close()
+
+	// invalid jobs are rejected
+	res, err := http.Post(api.URL+"/jobs", "application/json", strings.NewReader(`{"seeds": ["ftp://example.com"]}`))
+	require.Nil(t, err)
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res.Body.Close()
+
+	// jobs over the max parallelism and depth are rejected, including unlimited depths
+	for _, body := range []string{`"parallelism": 51`, `"depth": 11`, `"depth": 0, "all_hosts": true`} {
+		res, err = http.Post(api.URL+"/jobs", "application/json", strings.NewReader(`{"seeds": ["`+site.URL+`"], `+body+`}`))
+		require.Nil(t, err)
+		require.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+		res.Body.Close()
+	}
+
+	// submit a job
+	res, err = http.Post(api.URL+"/jobs", "application/json", bytes.NewBufferString(`{"seeds": ["`+site.URL+`"], "parallelism": 2}`))
+	require.Nil(t, err)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var job server.Job
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&job))
+	res.Body.Close()
+	require.Equal(t, "1", job.ID)
+	require.Equal(t, 1, job.Request.Depth)
+
+	// stream the progress until the job is done
+	job = lastEvent(t, api.URL+"/jobs/1/events")
+	require.Equal(t, server.StatusFinished, job.Status)
+	require.Equal(t, server.Progress{Processed: 2, Bytes: job.Progress.Bytes}, job.Progress)
+
+	// list the jobs
+	res, err = http.Get(api.URL + "/jobs")
+	require.Nil(t, err)
+	var jobs []server.Job
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&jobs))
+	res.Body.Close()
+	require.Len(t, jobs, 1)
+
+	// download the results
+	res, err = http.Get(api.URL + "/jobs/1/results?format=csv")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/csv", res.Header.Get("Content-Type"))
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	require.Equal(t, 3, strings.Count(string(body), "\n"))
+	require.True(t, strings.Contains(string(body), site.URL+",0,200,"))
+
+	res, err = http.Get(api.URL + "/jobs/1/results?format=unknown")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res.Body.Close()
+	res, err = http.Get(api.URL + "/jobs/2")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	res.Body.Close()
+
+	// delete the job
+	req, _ := http.NewRequest(http.MethodDelete, api.URL+"/jobs/1", nil)
+	res, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	res.Body.Close()
+	res, err = http.Get(api.URL + "/jobs/1")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+	res.Body.Close()
+}
+
+func TestServer_cancel(t *testing.T) {
+	// generate a test site that never answers until it is closed
+	release := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	defer site.Close()
+	defer close(release)
+
+	// only one job runs at a time
+	logger := zerolog.Nop()
+	s := server.New(&logger, server.SetMaxJobs(1))
+	defer s.Close()
+	api := httptest.NewServer(s)
+	defer api.Close()
+	for i := 0; i < 2; i++ {
+		res, err := http.Post(api.URL+"/jobs", "application/json", strings.NewReader(`{"seeds": ["`+site.URL+`"]}`))
+		require.Nil(t, err)
+		res.Body.Close()
+	}
+	time.Sleep(100 * time.Millisecond)
+	var job server.Job
+	res, err := http.Get(api.URL + "/jobs/2")
+	require.Nil(t, err)
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&job))
+	res.Body.Close()
+	require.Equal(t, server.StatusQueued, job.Status)
+
+	// results are only available once the job is done
+	res, err = http.Get(api.URL + "/jobs/2/results")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusConflict, res.StatusCode)
+	res.Body.Close()
+
+	// jobs can only be deleted once they are done
+	req, _ := http.NewRequest(http.MethodDelete, api.URL+"/jobs/2", nil)
+	res, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusConflict, res.StatusCode)
+	res.Body.Close()
+
+	// cancel the queued job
+	res, err = http.Post(api.URL+"/jobs/2/cancel", "application/json", nil)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	res.Body.Close()
+	job = lastEvent(t, api.URL+"/jobs/2/events")
+	require.Equal(t, server.StatusCancelled, job.Status)
+	res, err = http.Get(api.URL + "/jobs/2/results")
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+}
+
+// lastEvent reads the progress events until the stream is closed and returns the last one
+func lastEvent(t *testing.T, u string) server.Job {
+	res, err := http.Get(u)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	var job server.Job
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "data: ") {
+			require.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(scanner.Text(), "data: ")), &job))
+		}
+	}
+	return job
+}
@@ -0,0 +1,405 @@
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/export"
+	"github.com/pmdcosta/crawler/internal/fsutil"
+	"github.com/pmdcosta/crawler/internal/graph"
+	"github.com/pmdcosta/crawler/internal/orchestrator"
+)
+
+// output formats
+const (
+	Raw        = "raw"
+	JSON       = "json"
+	NDJSON     = "ndjson"
+	Importance = "importance"
+	GraphML    = "graphml"
+	GEXF       = "gexf"
+	DOT        = "dot"
+	CSV        = export.CSV
+	TSV        = export.TSV
+	HTML       = "html"
+	JUnit      = "junit"
+	SARIF      = "sarif"
+	Crawl      = "crawl"
+)
+
+// Stdout is the destination of the outputs printed to the standard output
+const Stdout = "-"
+
+// ErrUnknownOutput is returned when the output format is not known
+var ErrUnknownOutput = errors.New("unknown output, expected raw, json, ndjson, importance, graphml, gexf, dot, csv, tsv, html, junit, sarif or crawl")
+
+// ErrDuplicateStdout is returned when more than one output is written to the standard output
+var ErrDuplicateStdout = errors.New("only one output can be written to the standard output")
+
+// ErrTablesStdout is returned when the csv or tsv tables are written to the standard output instead of a directory
+var ErrTablesStdout = errors.New("csv and tsv outputs are written to a directory, not the standard output")
+
+// destinations are the default destinations of each output, the files are relative to the output directory
+var destinations = map[string]string{
+	Raw:        Stdout,
+	JSON:       Stdout,
+	NDJSON:     Stdout,
+	Importance: Stdout,
+	GraphML:    Stdout,
+	GEXF:       Stdout,
+	DOT:        Stdout,
+	CSV:        "",
+	TSV:        "",
+	HTML:       "report.html",
+	JUnit:      "junit.xml",
+	SARIF:      "results.sarif",
+	Crawl:      "crawl.json",
+}
+
+// Details returns whether an output format shows the titles of the pages and the anchor texts of the links
+// the details are only extracted from the pages when an output needs them
+func Details(format string) (title, anchors bool) {
+	switch format {
+	case Raw, JSON, Importance, JUnit, SARIF:
+		return false, false
+	case CSV, TSV:
+		return true, true
+	}
+	return true, false
+}
+
+// Sink receives the crawled pages and writes them somewhere
+type Sink interface {
+	// Write receives each page as soon as it is crawled, or once it fails for good
+	Write(result crawler.TaskResult) error
+	// Close receives the summary of the finished crawl
+	Close(summary *Summary) error
+}
+
+// Summary of a finished crawl
+type Summary struct {
+	Seed string
+	// all the tasks Processed and Failed
+	Processed map[string]crawler.TaskResult
+	Failed    map[string]crawler.TaskResult
+	// Processed and Failed pages sorted by url
+	Pages []crawler.TaskResult
+	// estimated importance of the Processed pages
+	Importance map[string]float64
+	// problems found by the checks
+	Findings []export.Finding
+}
+
+// Sinks sends the pages to multiple sinks
+type Sinks []Sink
+
+// Write writes the page to all the sinks, returning the first error
+func (s Sinks) Write(result crawler.TaskResult) error {
+	var first error
+	for _, sink := range s {
+		if err := sink.Write(result); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Summarized checks if a sink writes its output from the summary of the crawl, rather than only from each page
+func (s Sinks) Summarized() bool {
+	for _, sink := range s {
+		if _, ok := sink.(*stream); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Observer writes the pages to the sinks once they are crawled or fail for good, on the goroutine of the observer so
+// slow outputs don't stall the crawl, the pages that fail to be written are reported to fail
+func (s Sinks) Observer(fail func(result crawler.TaskResult, err error)) orchestrator.Observer {
+	return orchestrator.ObserverFunc(func(e orchestrator.Event) {
+		var result crawler.TaskResult
+		switch e := e.(type) {
+		case orchestrator.TaskSucceeded:
+			result = e.Result
+		case orchestrator.TaskFailed:
+			result = e.Result
+		default:
+			return
+		}
+		if err := s.Write(result); err != nil && fail != nil {
+			fail(result, err)
+		}
+	})
+}
+
+// Close closes all the sinks, returning the first error
+func (s Sinks) Close(summary *Summary) error {
+	var first error
+	for _, sink := range s {
+		if err := sink.Close(summary); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// config of the sinks
+type config struct {
+	// directory of the default destinations
+	dir string
+	// collapse of the graph outputs
+	collapse graph.Collapse
+	// severity failing the junit test cases
+	threshold string
+}
+
+// Option is an optimal configuration option that can be applied to a sink
+type Option func(c *config)
+
+// SetDir sets the directory where the outputs without a destination are written to
+func SetDir(dir string) Option {
+	return func(c *config) {
+		c.dir = dir
+	}
+}
+
+// SetCollapse collapses the nodes of the graph outputs
+func SetCollapse(collapse graph.Collapse) Option {
+	return func(c *config) {
+		c.collapse = collapse
+	}
+}
+
+// SetThreshold sets the severity of the findings failing the junit test cases
+func SetThreshold(severity string) Option {
+	return func(c *config) {
+		c.threshold = severity
+	}
+}
+
+// Parse creates a sink from its format and optional destination, as format or format=destination
+// a destination of - is the standard output, and csv and tsv destinations are directories
+func Parse(spec string, opts ...Option) (Sink, error) {
+	format, dest := spec, ""
+	if i := strings.Index(spec, "="); i >= 0 {
+		format, dest = spec[:i], spec[i+1:]
+	}
+	return New(format, dest, opts...)
+}
+
+// ParseAll creates the sinks of the outputs, only one of them can be written to the standard output
+func ParseAll(specs []string, opts ...Option) (Sinks, error) {
+	var sinks Sinks
+	var stdout bool
+	for _, spec := range specs {
+		s, err := Parse(spec, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, spec)
+		}
+		if toStdout(s) {
+			if stdout {
+				return nil, fmt.Errorf("%w: %s", ErrDuplicateStdout, spec)
+			}
+			stdout = true
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// toStdout checks if the sink writes to the standard output
+func toStdout(s Sink) bool {
+	switch s := s.(type) {
+	case *file:
+		return s.dest == Stdout
+	case *stream:
+		return s.f == nil
+	}
+	return false
+}
+
+// New creates a sink writing the output format to the destination, or to its default destination if empty
+func New(format, dest string, opts ...Option) (Sink, error) {
+	c := config{dir: ".", threshold: export.SeverityError}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	d, found := destinations[format]
+	if !found {
+		return nil, ErrUnknownOutput
+	}
+	if dest == "" {
+		dest = d
+		if dest != Stdout {
+			dest = filepath.Join(c.dir, dest)
+		}
+	}
+
+	switch format {
+	case NDJSON:
+		s, err := newStream(dest)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case CSV, TSV:
+		if dest == Stdout {
+			return nil, ErrTablesStdout
+		}
+		return &tables{dir: dest, format: format}, nil
+	}
+	return &file{dest: dest, write: writer(format, c)}, nil
+}
+
+// WriteSummary writes an output format from the summary of a crawl, the csv and tsv formats only write the pages table
+func WriteSummary(w io.Writer, format string, summary *Summary, opts ...Option) error {
+	c := config{threshold: export.SeverityError}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if _, found := destinations[format]; !found {
+		return ErrUnknownOutput
+	}
+	return writer(format, c)(w, summary)
+}
+
+// writer returns the function writing an output format from the summary
+func writer(format string, c config) func(w io.Writer, s *Summary) error {
+	return func(w io.Writer, s *Summary) error {
+		switch format {
+		case Raw:
+			_, err := fmt.Fprintln(w, hits(s.Processed))
+			return err
+		case JSON:
+			return writeJSON(w, hits(s.Processed))
+		case NDJSON:
+			enc := json.NewEncoder(w)
+			for _, p := range s.Pages {
+				r := export.NewRecord(p)
+				r.Importance = s.Importance[r.URL]
+				if err := enc.Encode(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		case Importance:
+			return writeJSON(w, s.Importance)
+		case GraphML:
+			return graph.NewExport(s.Processed, c.collapse).WriteGraphML(w)
+		case GEXF:
+			return graph.NewExport(s.Processed, c.collapse).WriteGEXF(w)
+		case DOT:
+			return graph.NewExport(s.Processed, c.collapse).WriteDOT(w)
+		case CSV:
+			return export.WritePages(w, ',', s.Pages)
+		case TSV:
+			return export.WritePages(w, '\t', s.Pages)
+		case HTML:
+			return export.WriteHTML(w, s.Seed, s.Pages)
+		case JUnit:
+			return export.WriteJUnit(w, s.Seed, s.Pages, s.Findings, c.threshold)
+		case SARIF:
+			return export.WriteSARIF(w, s.Findings)
+		default:
+			return export.WriteCrawl(w, s.Seed, s.Pages, s.Importance)
+		}
+	}
+}
+
+// file writes an output once the crawl is finished
+type file struct {
+	dest  string
+	write func(w io.Writer, s *Summary) error
+}
+
+// Write ignores the page, the output is written from the summary
+func (f *file) Write(crawler.TaskResult) error {
+	return nil
+}
+
+// Close writes the output
+func (f *file) Close(summary *Summary) error {
+	if f.dest == Stdout {
+		return f.write(os.Stdout, summary)
+	}
+	return fsutil.WriteFile(f.dest, func(w io.Writer) error {
+		return f.write(w, summary)
+	})
+}
+
+// stream writes a json line for each page as it is crawled
+type stream struct {
+	// file of the stream, nil for the standard output
+	f   *os.File
+	enc *json.Encoder
+}
+
+// newStream creates the destination of the stream
+func newStream(dest string) (*stream, error) {
+	if dest == Stdout {
+		return &stream{enc: json.NewEncoder(os.Stdout)}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return nil, err
+	}
+	return &stream{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Write writes the page as a json line, without its importance which is only known once the crawl is finished
+func (s *stream) Write(result crawler.TaskResult) error {
+	return s.enc.Encode(export.NewRecord(result))
+}
+
+// Close closes the destination
+func (s *stream) Close(*Summary) error {
+	if s.f == nil {
+		return nil
+	}
+	return s.f.Close()
+}
+
+// tables writes the pages and links tables to a directory
+type tables struct {
+	dir    string
+	format string
+}
+
+// Write ignores the page, the tables are written from the summary
+func (t *tables) Write(crawler.TaskResult) error {
+	return nil
+}
+
+// Close writes the tables
+func (t *tables) Close(summary *Summary) error {
+	return export.WriteTables(t.dir, t.format, summary.Pages)
+}
+
+// hits returns the links of the Processed pages
+func hits(processed map[string]crawler.TaskResult) map[string]map[string]int {
+	var result = make(map[string]map[string]int)
+	for _, p := range processed {
+		result[p.URL.String()] = p.Children
+	}
+	return result
+}
+
+// writeJSON writes a value as a json line
+func writeJSON(w io.Writer, v interface{}) error {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(j))
+	return err
+}
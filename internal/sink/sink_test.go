@@ -0,0 +1,117 @@
+package sink_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/sink"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	_, err := sink.Parse("unknown")
+	require.Equal(t, sink.ErrUnknownOutput, err)
+	_, err = sink.Parse("unknown=out.txt")
+	require.Equal(t, sink.ErrUnknownOutput, err)
+
+	// the tables are written to a directory
+	for _, spec := range []string{"csv=-", "tsv=-"} {
+		_, err = sink.Parse(spec)
+		require.Equal(t, sink.ErrTablesStdout, err, spec)
+	}
+	sinks, err := sink.ParseAll([]string{"json", "csv=tables"})
+	require.Nil(t, err)
+	require.Len(t, sinks, 2)
+
+	// only one output is printed to the standard output
+	sinks, err = sink.ParseAll([]string{"json", "html=-", "sarif"})
+	require.True(t, errors.Is(err, sink.ErrDuplicateStdout))
+	require.Nil(t, sinks)
+	sinks, err = sink.ParseAll([]string{"json", "raw=hits.txt"})
+	require.Nil(t, err)
+	require.Len(t, sinks, 2)
+	require.True(t, sinks.Summarized())
+
+	// the streamed outputs don't need the summary
+	sinks, err = sink.ParseAll([]string{"ndjson"})
+	require.Nil(t, err)
+	require.False(t, sinks.Summarized())
+	_, err = sink.ParseAll([]string{"json", "unknown"})
+	require.True(t, errors.Is(err, sink.ErrUnknownOutput))
+}
+
+func TestSinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// stream the pages to a file and write the json output and the html report once the crawl is finished
+	stream, err := sink.Parse("ndjson="+filepath.Join(dir, "pages.ndjson"), sink.SetDir(dir))
+	require.Nil(t, err)
+	hits, err := sink.Parse("json="+filepath.Join(dir, "hits.json"), sink.SetDir(dir))
+	require.Nil(t, err)
+	report, err := sink.Parse("html", sink.SetDir(dir))
+	require.Nil(t, err)
+	sinks := sink.Sinks{stream, hits, report}
+
+	u, _ := url.Parse("https://monzo.com/")
+	page := crawler.TaskResult{Task: crawler.Task{URL: u}, Children: map[string]int{"https://monzo.com/about": 1}}
+	require.Nil(t, sinks.Write(page))
+
+	// the pages are streamed as they are crawled
+	data, err := ioutil.ReadFile(filepath.Join(dir, "pages.ndjson"))
+	require.Nil(t, err)
+	require.Equal(t, `{"url":"https://monzo.com/","depth":0,"links":{"https://monzo.com/about":1}}`+"\n", string(data))
+	_, err = os.Stat(filepath.Join(dir, "hits.json"))
+	require.True(t, os.IsNotExist(err))
+
+	// the other outputs are written from the summary
+	summary := sink.Summary{
+		Seed:      u.String(),
+		Processed: map[string]crawler.TaskResult{u.String(): page},
+		Pages:     []crawler.TaskResult{page},
+	}
+	require.Nil(t, sinks.Close(&summary))
+	data, err = ioutil.ReadFile(filepath.Join(dir, "hits.json"))
+	require.Nil(t, err)
+	require.Equal(t, `{"https://monzo.com/":{"https://monzo.com/about":1}}`+"\n", string(data))
+	data, err = ioutil.ReadFile(filepath.Join(dir, "report.html"))
+	require.Nil(t, err)
+	require.True(t, strings.Contains(string(data), "https://monzo.com/"))
+}
+
+func TestSinks_importance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	u, _ := url.Parse("https://monzo.com/")
+	page := crawler.TaskResult{Task: crawler.Task{URL: u}}
+	summary := sink.Summary{
+		Seed:       u.String(),
+		Processed:  map[string]crawler.TaskResult{u.String(): page},
+		Pages:      []crawler.TaskResult{page},
+		Importance: map[string]float64{u.String(): 1},
+	}
+
+	// the streamed records are written before the importance is known
+	stream, err := sink.Parse("ndjson=" + filepath.Join(dir, "pages.ndjson"))
+	require.Nil(t, err)
+	require.Nil(t, stream.Write(page))
+	require.Nil(t, stream.Close(&summary))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "pages.ndjson"))
+	require.Nil(t, err)
+	require.Equal(t, `{"url":"https://monzo.com/","depth":0,"links":{}}`+"\n", string(data))
+
+	// the records written from the summary have it
+	var out bytes.Buffer
+	require.Nil(t, sink.WriteSummary(&out, sink.NDJSON, &summary))
+	require.Equal(t, `{"url":"https://monzo.com/","depth":0,"importance":1,"links":{}}`+"\n", out.String())
+}
@@ -0,0 +1,368 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/rs/zerolog"
+
+	// pure go sqlite driver, so the crawler is still built without cgo
+	_ "modernc.org/sqlite"
+)
+
+// ErrNoRun is returned when a page is saved before a run is started
+var ErrNoRun = errors.New("no crawl run started")
+
+// schema of the database, each crawl is a run and its pages are stored in normalized tables
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	seed TEXT NOT NULL,
+	started_at TEXT NOT NULL,
+	finished_at TEXT
+);
+CREATE TABLE IF NOT EXISTS pages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id INTEGER NOT NULL REFERENCES runs(id),
+	url TEXT NOT NULL,
+	depth INTEGER NOT NULL,
+	tries INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	UNIQUE (run_id, url)
+);
+CREATE TABLE IF NOT EXISTS responses (
+	page_id INTEGER PRIMARY KEY REFERENCES pages(id),
+	final_url TEXT NOT NULL,
+	status INTEGER NOT NULL,
+	content_type TEXT NOT NULL,
+	bytes INTEGER NOT NULL,
+	fetched_at TEXT NOT NULL,
+	response_time_ms REAL NOT NULL
+);
+CREATE TABLE IF NOT EXISTS redirects (
+	page_id INTEGER NOT NULL REFERENCES pages(id),
+	hop INTEGER NOT NULL,
+	url TEXT NOT NULL,
+	status INTEGER NOT NULL,
+	PRIMARY KEY (page_id, hop)
+);
+CREATE TABLE IF NOT EXISTS links (
+	page_id INTEGER NOT NULL REFERENCES pages(id),
+	target TEXT NOT NULL,
+	count INTEGER NOT NULL,
+	PRIMARY KEY (page_id, target)
+);
+CREATE TABLE IF NOT EXISTS errors (
+	page_id INTEGER PRIMARY KEY REFERENCES pages(id),
+	message TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS anchors (
+	page_id INTEGER NOT NULL REFERENCES pages(id),
+	target TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	text TEXT NOT NULL,
+	PRIMARY KEY (page_id, target, position)
+);
+CREATE INDEX IF NOT EXISTS links_target ON links (target);
+`
+
+// SQLite stores the crawled pages in a sqlite database, a database can keep multiple crawl runs
+type SQLite struct {
+	logger *zerolog.Logger
+	db     *sql.DB
+
+	// id of the current run
+	run int64
+}
+
+// Option is an optimal configuration option that can be applied to the storage
+type Option func(s *SQLite)
+
+// New opens, or creates, the sqlite database in the path
+func New(logger *zerolog.Logger, path string, opts ...Option) (*SQLite, error) {
+	l := logger.With().Str("pkg", "storage").Logger()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only supports a single writer
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	s := SQLite{
+		logger: &l,
+		db:     db,
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return &s, nil
+}
+
+// DB returns the underlying database, to run queries over the stored crawls
+func (s *SQLite) DB() *sql.DB {
+	return s.db
+}
+
+// Run returns the id of the current run
+func (s *SQLite) Run() int64 {
+	return s.run
+}
+
+// StartRun starts a new crawl run from the seed, the pages saved afterwards belong to the run
+func (s *SQLite) StartRun(seed string) error {
+	res, err := s.db.Exec("INSERT INTO runs (seed, started_at) VALUES (?, ?)", seed, now())
+	if err != nil {
+		return err
+	}
+	s.run, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	s.logger.Info().Int64("run", s.run).Msg("crawl run started")
+	return nil
+}
+
+// FinishRun marks the current run as finished
+func (s *SQLite) FinishRun() error {
+	if s.run == 0 {
+		return ErrNoRun
+	}
+	_, err := s.db.Exec("UPDATE runs SET finished_at = ? WHERE id = ?", now(), s.run)
+	return err
+}
+
+// Save stores a processed or failed page in the current run
+func (s *SQLite) Save(result crawler.TaskResult) error {
+	if s.run == 0 {
+		return ErrNoRun
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := save(tx, s.run, result); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Load reads the pages of a run, split into the processed pages and the pages that failed without a response
+// the response headers other than the content type, the timings and the bodies are not stored
+func (s *SQLite) Load(run int64) (processed, failed map[string]crawler.TaskResult, err error) {
+	var pages = make(map[int64]*crawler.TaskResult)
+	rows, err := s.db.Query("SELECT id, url, depth, tries, title FROM pages WHERE run_id = ?", run)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = scan(rows, func() error {
+		var id int64
+		var u string
+		var p crawler.TaskResult
+		if err := rows.Scan(&id, &u, &p.Depth, &p.Tries, &p.Title); err != nil {
+			return err
+		}
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return err
+		}
+		p.URL = parsed
+		pages[id] = &p
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the rows of each table are matched with the pages of the run
+	rows, err = s.db.Query(`SELECT page_id, final_url, status, content_type, bytes, fetched_at, response_time_ms
+		FROM responses JOIN pages ON pages.id = responses.page_id WHERE run_id = ?`, run)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = scan(rows, func() error {
+		var id int64
+		var finalURL, contentType, fetched string
+		var duration float64
+		var r crawler.Response
+		if err := rows.Scan(&id, &finalURL, &r.StatusCode, &contentType, &r.Size, &fetched, &duration); err != nil {
+			return err
+		}
+		r.URL, _ = url.Parse(finalURL)
+		r.Header = http.Header{}
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		r.Time, _ = time.Parse(time.RFC3339Nano, fetched)
+		r.Duration = time.Duration(duration * float64(time.Millisecond))
+		pages[id].Response = &r
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err = s.db.Query("SELECT page_id, redirects.url, redirects.status FROM redirects JOIN pages ON pages.id = redirects.page_id WHERE run_id = ? ORDER BY page_id, hop", run)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = scan(rows, func() error {
+		var id int64
+		var u string
+		var redirect crawler.Redirect
+		if err := rows.Scan(&id, &u, &redirect.StatusCode); err != nil {
+			return err
+		}
+		redirect.URL, _ = url.Parse(u)
+		if r := pages[id].Response; r != nil {
+			r.Redirects = append(r.Redirects, redirect)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err = s.db.Query("SELECT page_id, target, count FROM links JOIN pages ON pages.id = links.page_id WHERE run_id = ?", run)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = scan(rows, func() error {
+		var id int64
+		var target string
+		var count int
+		if err := rows.Scan(&id, &target, &count); err != nil {
+			return err
+		}
+		if pages[id].Children == nil {
+			pages[id].Children = make(map[string]int)
+		}
+		pages[id].Children[target] = count
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err = s.db.Query("SELECT page_id, target, text FROM anchors JOIN pages ON pages.id = anchors.page_id WHERE run_id = ? ORDER BY page_id, target, position", run)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = scan(rows, func() error {
+		var id int64
+		var target, text string
+		if err := rows.Scan(&id, &target, &text); err != nil {
+			return err
+		}
+		if pages[id].Anchors == nil {
+			pages[id].Anchors = make(map[string][]string)
+		}
+		pages[id].Anchors[target] = append(pages[id].Anchors[target], text)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err = s.db.Query("SELECT page_id, message FROM errors JOIN pages ON pages.id = errors.page_id WHERE run_id = ?", run)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = scan(rows, func() error {
+		var id int64
+		var message string
+		if err := rows.Scan(&id, &message); err != nil {
+			return err
+		}
+		failure := errors.New(message)
+		pages[id].Error = &failure
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	processed, failed = make(map[string]crawler.TaskResult), make(map[string]crawler.TaskResult)
+	for _, p := range pages {
+		if p.Error != nil && p.Response == nil {
+			failed[p.URL.String()] = *p
+		} else {
+			processed[p.URL.String()] = *p
+		}
+	}
+	return processed, failed, nil
+}
+
+// scan calls the function for each row and closes the rows
+func scan(rows *sql.Rows, f func() error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Close closes the database
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// save stores a page and its response, redirects, links and error
+func save(tx *sql.Tx, run int64, result crawler.TaskResult) error {
+	res, err := tx.Exec("INSERT INTO pages (run_id, url, depth, tries, title) VALUES (?, ?, ?, ?, ?)",
+		run, result.URL.String(), result.Depth, result.Tries, result.Title)
+	if err != nil {
+		return err
+	}
+	page, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if r := result.Response; r != nil {
+		finalURL := result.URL.String()
+		if r.URL != nil {
+			finalURL = r.URL.String()
+		}
+		_, err := tx.Exec("INSERT INTO responses (page_id, final_url, status, content_type, bytes, fetched_at, response_time_ms) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			page, finalURL, r.StatusCode, r.Header.Get("Content-Type"), r.Size, r.Time.UTC().Format(time.RFC3339Nano), r.Duration.Seconds()*1000)
+		if err != nil {
+			return err
+		}
+		for i, redirect := range r.Redirects {
+			_, err := tx.Exec("INSERT INTO redirects (page_id, hop, url, status) VALUES (?, ?, ?, ?)",
+				page, i, redirect.URL.String(), redirect.StatusCode)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for target, count := range result.Children {
+		if _, err := tx.Exec("INSERT INTO links (page_id, target, count) VALUES (?, ?, ?)", page, target, count); err != nil {
+			return err
+		}
+	}
+	for target, texts := range result.Anchors {
+		for i, text := range texts {
+			if _, err := tx.Exec("INSERT INTO anchors (page_id, target, position, text) VALUES (?, ?, ?, ?)", page, target, i, text); err != nil {
+				return err
+			}
+		}
+	}
+	if result.Error != nil && *result.Error != nil {
+		if _, err := tx.Exec("INSERT INTO errors (page_id, message) VALUES (?, ?)", page, (*result.Error).Error()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// now returns the current time in the format stored in the database
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
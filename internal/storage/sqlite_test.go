@@ -0,0 +1,97 @@
+package storage_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/storage"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := zerolog.Nop()
+	s, err := storage.New(&logger, filepath.Join(dir, "crawl.db"))
+	require.Nil(t, err)
+	defer s.Close()
+
+	// pages can only be saved in a run
+	home, _ := url.Parse("https://monzo.com/")
+	about, _ := url.Parse("https://monzo.com/about")
+	require.Equal(t, storage.ErrNoRun, s.Save(crawler.TaskResult{Task: crawler.Task{URL: home}}))
+
+	// save a run with a page and a failed page
+	for run := 0; run < 2; run++ {
+		require.Nil(t, s.StartRun(home.String()))
+		require.Nil(t, s.Save(crawler.TaskResult{
+			Task:     crawler.Task{URL: home},
+			Children: map[string]int{"https://monzo.com/about": 2, "https://monzo.com/blog": 1},
+			Title:    "Monzo",
+			Anchors:  map[string][]string{"https://monzo.com/about": {"About", "Team"}},
+			Response: &crawler.Response{
+				URL:        home,
+				Redirects:  []crawler.Redirect{{URL: home, StatusCode: http.StatusMovedPermanently}},
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/html"}},
+				Size:       100,
+				Duration:   time.Second,
+			},
+		}))
+		failure := errors.New("timeout")
+		require.Nil(t, s.Save(crawler.TaskResult{Task: crawler.Task{URL: about, Depth: 1, Tries: 4}, Error: &failure}))
+		require.Nil(t, s.FinishRun())
+	}
+	require.Equal(t, int64(2), s.Run())
+
+	// every run keeps its own pages
+	var runs, pages, links, redirects int
+	require.Nil(t, s.DB().QueryRow("SELECT COUNT(*) FROM runs WHERE finished_at IS NOT NULL").Scan(&runs))
+	require.Nil(t, s.DB().QueryRow("SELECT COUNT(*) FROM pages WHERE run_id = 2").Scan(&pages))
+	require.Nil(t, s.DB().QueryRow("SELECT SUM(count) FROM links JOIN pages ON pages.id = links.page_id WHERE run_id = 2").Scan(&links))
+	require.Nil(t, s.DB().QueryRow("SELECT COUNT(*) FROM redirects").Scan(&redirects))
+	require.Equal(t, 2, runs)
+	require.Equal(t, 2, pages)
+	require.Equal(t, 3, links)
+	require.Equal(t, 2, redirects)
+
+	// the responses and errors are joined with the pages
+	var status int
+	var contentType string
+	var duration float64
+	require.Nil(t, s.DB().QueryRow("SELECT status, content_type, response_time_ms FROM responses JOIN pages ON pages.id = responses.page_id WHERE url = ? AND run_id = 1", home.String()).Scan(&status, &contentType, &duration))
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "text/html", contentType)
+	require.Equal(t, 1000.0, duration)
+
+	var message string
+	var tries int
+	require.Nil(t, s.DB().QueryRow("SELECT message, tries FROM errors JOIN pages ON pages.id = errors.page_id WHERE url = ? AND run_id = 1", about.String()).Scan(&message, &tries))
+	require.Equal(t, "timeout", message)
+	require.Equal(t, 4, tries)
+
+	// the pages of a run are read back
+	processed, failed, err := s.Load(2)
+	require.Nil(t, err)
+	require.Len(t, processed, 1)
+	require.Len(t, failed, 1)
+	page := processed[home.String()]
+	require.Equal(t, "Monzo", page.Title)
+	require.Equal(t, map[string]int{"https://monzo.com/about": 2, "https://monzo.com/blog": 1}, page.Children)
+	require.Equal(t, []string{"About", "Team"}, page.Anchors["https://monzo.com/about"])
+	require.Equal(t, http.StatusOK, page.Response.StatusCode)
+	require.Equal(t, "text/html", page.Response.Header.Get("Content-Type"))
+	require.Equal(t, time.Second, page.Response.Duration)
+	require.Equal(t, []crawler.Redirect{{URL: home, StatusCode: http.StatusMovedPermanently}}, page.Response.Redirects)
+	require.Equal(t, "timeout", (*failed[about.String()].Error).Error())
+	require.Equal(t, 4, failed[about.String()].Tries)
+}
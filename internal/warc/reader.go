@@ -0,0 +1,99 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidRecord is returned when a record is not a valid warc record
+var ErrInvalidRecord = errors.New("invalid warc record")
+
+// Record is a warc record read from a file
+type Record struct {
+	// named fields of the record
+	Header textproto.MIMEHeader
+	// content block of the record
+	Block []byte
+}
+
+// Type returns the type of the record
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// Response parses the http response in the block of a response or revisit record
+func (r *Record) Response() (*http.Response, []byte, error) {
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(r.Block)), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, body, nil
+}
+
+// Reader reads the records of a warc file, either gzip compressed or not
+type Reader struct {
+	reader *bufio.Reader
+}
+
+// NewReader instantiates a new reader
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gz)
+	}
+	return &Reader{reader: br}, nil
+}
+
+// Next returns the next record, io.EOF is returned when there are no more records
+func (r *Reader) Next() (*Record, error) {
+	// skip the empty lines between records
+	var line string
+	for line == "" {
+		l, err := r.reader.ReadString('\n')
+		line = strings.TrimSpace(l)
+		if err == io.EOF && line == "" {
+			return nil, io.EOF
+		} else if err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, ErrInvalidRecord
+	}
+
+	// read the named fields and the block
+	header, err := textproto.NewReader(r.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidRecord
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, block); err != nil {
+		return nil, err
+	}
+	return &Record{Header: header, Block: block}, nil
+}
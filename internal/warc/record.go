@@ -0,0 +1,205 @@
+package warc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	version    = "WARC/1.1"
+	dateFormat = "2006-01-02T15:04:05Z"
+	cdxFormat  = "20060102150405"
+
+	typeInfo     = "warcinfo"
+	typeRequest  = "request"
+	typeResponse = "response"
+	typeRevisit  = "revisit"
+	typeMetadata = "metadata"
+
+	contentRequest  = "application/http;msgtype=request"
+	contentResponse = "application/http;msgtype=response"
+	contentFields   = "application/warc-fields"
+
+	profileNotModified = "http://netpreserve.org/warc/1.1/revisit/server-not-modified"
+	profileIdentical   = "http://netpreserve.org/warc/1.1/revisit/identical-payload-digest"
+
+	cdxHeader = " CDX N b a m s k r M S V g"
+)
+
+// skipHeaders are the response headers that no longer match the recorded body
+// the body is recorded decoded and without chunks, so its length is set again
+var skipHeaders = map[string]struct{}{
+	"Content-Encoding":  {},
+	"Transfer-Encoding": {},
+	"Content-Length":    {},
+}
+
+// record is a single warc record
+type record struct {
+	// named fields in the order they are written
+	fields [][2]string
+	block  []byte
+}
+
+// newRecord instantiates a new record of a type
+func newRecord(t string, date time.Time, contentType string, block []byte) *record {
+	r := record{block: block}
+	r.add("WARC-Type", t)
+	r.add("WARC-Record-ID", newID())
+	r.add("WARC-Date", date.UTC().Format(dateFormat))
+	if contentType != "" {
+		r.add("Content-Type", contentType)
+	}
+	return &r
+}
+
+// add adds a named field to the record
+func (r *record) add(name, value string) {
+	r.fields = append(r.fields, [2]string{name, value})
+}
+
+// get returns the value of a named field
+func (r *record) get(name string) string {
+	for _, f := range r.fields {
+		if strings.EqualFold(f[0], name) {
+			return f[1]
+		}
+	}
+	return ""
+}
+
+// bytes serializes the record
+func (r *record) bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(version + "\r\n")
+	for _, f := range r.fields {
+		fmt.Fprintf(&buf, "%s: %s\r\n", f[0], f[1])
+	}
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(r.block))
+	buf.Write(r.block)
+	buf.WriteString("\r\n\r\n")
+	return buf.Bytes()
+}
+
+// newID returns a new record id
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// digest returns the sha1 digest of the data in the warc format
+func digest(data []byte) string {
+	h := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(h[:])
+}
+
+// requestBlock returns the http request message
+func requestBlock(method string, u *url.URL, header http.Header) []byte {
+	if method == "" {
+		method = http.MethodGet
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", method, u.RequestURI())
+	fmt.Fprintf(&buf, "Host: %s\r\n", u.Host)
+	writeHeader(&buf, header, nil)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// responseHeader returns the http response status line and headers
+func responseHeader(status int, header http.Header, size int) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	writeHeader(&buf, header, skipHeaders)
+	if status != http.StatusNotModified {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n", size)
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// writeHeader writes the headers sorted by name
+func writeHeader(buf *bytes.Buffer, header http.Header, skip map[string]struct{}) {
+	var names []string
+	for k := range header {
+		if _, found := skip[http.CanonicalHeaderKey(k)]; !found {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, v := range header[k] {
+			fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+		}
+	}
+}
+
+// fieldsBlock returns a warc-fields block
+func fieldsBlock(fields [][2]string) []byte {
+	var buf bytes.Buffer
+	for _, f := range fields {
+		fmt.Fprintf(&buf, "%s: %s\r\n", f[0], f[1])
+	}
+	return buf.Bytes()
+}
+
+// surt returns the sort-friendly form of an url used as the cdx key
+func surt(u *url.URL) string {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	parts := strings.Split(host, ".")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	key := strings.Join(parts, ",")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		key += ":" + port
+	}
+	key += ")" + strings.ToLower(u.EscapedPath())
+	if u.RawQuery != "" {
+		query := strings.Split(strings.ToLower(u.RawQuery), "&")
+		sort.Strings(query)
+		key += "?" + strings.Join(query, "&")
+	}
+	return key
+}
+
+// cdxLine returns the cdx index line of a record in the N b a m s k r M S V g format
+func cdxLine(u *url.URL, date time.Time, mime string, status int, payloadDigest, redirect string, length, offset int64, file string) string {
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = mime[:i]
+	}
+	fields := []string{
+		surt(u),
+		date.UTC().Format(cdxFormat),
+		u.String(),
+		orDash(strings.TrimSpace(mime)),
+		strconv.Itoa(status),
+		orDash(strings.TrimPrefix(payloadDigest, "sha1:")),
+		orDash(redirect),
+		"-",
+		strconv.FormatInt(length, 10),
+		strconv.FormatInt(offset, 10),
+		file,
+	}
+	return strings.Join(fields, " ")
+}
+
+// orDash returns a dash for empty cdx fields
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.Replace(s, " ", "%20", -1)
+}
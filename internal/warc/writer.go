@@ -0,0 +1,322 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/fsutil"
+	"github.com/rs/zerolog"
+)
+
+// Writer records the crawled responses in rotated gzip compressed warc files with a cdx index
+// the cdx lines are appended to the index as the records are written, so it survives a crash, and the index is sorted on close
+// the index is shared by the crawls written to the same directory with the same prefix
+type Writer struct {
+	logger *zerolog.Logger
+
+	// directory where the files are written
+	dir string
+	// prefix of the file names
+	prefix string
+	// size after which a new file is started
+	maxSize int64
+
+	mu sync.Mutex
+	// file being written
+	file *os.File
+	name string
+	size int64
+	// number of files written
+	serial int
+	// cdx index the lines are appended to
+	index *os.File
+}
+
+// Option is an optimal configuration option that can be applied to a writer
+type Option func(w *Writer)
+
+// New instantiates a new writer, the files are only created when the first record is written
+func New(logger *zerolog.Logger, dir string, opts ...Option) *Writer {
+	l := logger.With().Str("pkg", "warc").Logger()
+	w := Writer{
+		logger:  &l,
+		dir:     dir,
+		prefix:  "crawl",
+		maxSize: 1 << 30,
+	}
+	for _, opt := range opts {
+		opt(&w)
+	}
+	return &w
+}
+
+// SetPrefix sets the prefix of the file names
+func SetPrefix(prefix string) Option {
+	return func(w *Writer) {
+		w.prefix = prefix
+	}
+}
+
+// SetMaxSize sets the size in bytes after which a new file is started
+func SetMaxSize(n int64) Option {
+	return func(w *Writer) {
+		w.maxSize = n
+	}
+}
+
+// PostProcess records the response of a task along with its outlinks
+// it can be used as a worker post-processor
+func (w *Writer) PostProcess(ctx context.Context, result *crawler.TaskResult) error {
+	if result.Response == nil {
+		return nil
+	}
+	return w.Write(result.URL, result.Response, result.Children)
+}
+
+// Write records the request, response and metadata records of a response
+// responses served from the cache are recorded as revisit records referring to the original capture,
+// as not modified when they were revalidated and as identical payload when they were not requested
+func (w *Writer) Write(u *url.URL, res *crawler.Response, outlinks map[string]int) error {
+	target := u
+	if res.URL != nil {
+		target = res.URL
+	}
+	date := res.Time
+	if date.IsZero() {
+		date = time.Now()
+	}
+	ip := res.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	// request record
+	request := newRecord(typeRequest, date, contentRequest, requestBlock(res.Method, target, res.RequestHeader))
+	request.add("WARC-Target-URI", target.String())
+
+	// response record
+	payload := digest(res.Body)
+	var response *record
+	switch res.Cache {
+	case crawler.CacheRevalidated:
+		response = newRecord(typeRevisit, date, contentResponse, responseHeader(http.StatusNotModified, res.Header, 0))
+		response.add("WARC-Profile", profileNotModified)
+	case crawler.CacheHit:
+		response = newRecord(typeRevisit, date, contentResponse, responseHeader(res.StatusCode, res.Header, len(res.Body)))
+		response.add("WARC-Profile", profileIdentical)
+	default:
+		response = newRecord(typeResponse, date, contentResponse, append(responseHeader(res.StatusCode, res.Header, len(res.Body)), res.Body...))
+	}
+	response.add("WARC-Target-URI", target.String())
+	if res.Cache != "" {
+		response.add("WARC-Refers-To-Target-URI", target.String())
+		if !res.Cached.IsZero() {
+			response.add("WARC-Refers-To-Date", res.Cached.UTC().Format(dateFormat))
+		}
+	}
+	if ip != "" {
+		response.add("WARC-IP-Address", ip)
+	}
+	response.add("WARC-Concurrent-To", request.get("WARC-Record-ID"))
+	response.add("WARC-Block-Digest", digest(response.block))
+	response.add("WARC-Payload-Digest", payload)
+	request.add("WARC-Concurrent-To", response.get("WARC-Record-ID"))
+	request.add("WARC-Block-Digest", digest(request.block))
+
+	// metadata record
+	fields := [][2]string{{"fetchTimeMs", strconv.FormatInt(int64(res.Duration/time.Millisecond), 10)}}
+	var links []string
+	for l := range outlinks {
+		links = append(links, l)
+	}
+	sort.Strings(links)
+	for _, l := range links {
+		fields = append(fields, [2]string{"outlink", l})
+	}
+	metadata := newRecord(typeMetadata, date, contentFields, fieldsBlock(fields))
+	metadata.add("WARC-Target-URI", target.String())
+	metadata.add("WARC-Refers-To", response.get("WARC-Record-ID"))
+	metadata.add("WARC-Block-Digest", digest(metadata.block))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.rotate(); err != nil {
+		return err
+	}
+	if _, _, err := w.write(request); err != nil {
+		return err
+	}
+	offset, length, err := w.write(response)
+	if err != nil {
+		return err
+	}
+	if _, _, err := w.write(metadata); err != nil {
+		return err
+	}
+	if res.Cache == "" {
+		if err := w.indexLine(cdxLine(target, date, res.Header.Get("Content-Type"), res.StatusCode, payload, res.Header.Get("Location"), length, offset, w.name)); err != nil {
+			return err
+		}
+	}
+	w.logger.Debug().Str("url", target.String()).Str("file", w.name).Msg("recorded response")
+	return nil
+}
+
+// indexLine appends a line to the cdx index, creating it with its header if it doesn't exist yet
+func (w *Writer) indexLine(line string) error {
+	if w.index == nil {
+		f, err := os.OpenFile(filepath.Join(w.dir, w.prefix+".cdx"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err == nil && info.Size() == 0 {
+			_, err = f.WriteString(cdxHeader + "\n")
+		}
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		w.index = f
+	}
+	_, err := w.index.WriteString(line + "\n")
+	return err
+}
+
+// Close closes the current file and writes the sorted cdx index
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	if w.index == nil {
+		return nil
+	}
+	if err := w.index.Close(); err != nil {
+		return err
+	}
+	w.index = nil
+
+	// the lines of this crawl were appended to the ones of the previous crawls, they are all sorted together
+	path := filepath.Join(w.dir, w.prefix+".cdx")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for _, l := range strings.Split(string(data), "\n") {
+		if l != "" && l != cdxHeader {
+			lines = append(lines, l)
+		}
+	}
+	sort.Strings(lines)
+	return fsutil.WriteFile(path, func(f io.Writer) error {
+		_, err := io.WriteString(f, cdxHeader+"\n"+strings.Join(lines, "\n")+"\n")
+		return err
+	})
+}
+
+// rotate starts a new file if there is none or the current one is full
+func (w *Writer) rotate() error {
+	if w.file != nil && w.size < w.maxSize {
+		return nil
+	}
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		return err
+	}
+	// the files of a previous crawl started in the same second are kept
+	var f *os.File
+	for {
+		w.serial++
+		w.name = fmt.Sprintf("%s-%s-%05d.warc.gz", w.prefix, time.Now().UTC().Format(cdxFormat), w.serial)
+		var err error
+		f, err = os.OpenFile(filepath.Join(w.dir, w.name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+	}
+	w.file = f
+	w.size = 0
+	w.logger.Info().Str("file", w.name).Msg("started warc file")
+
+	// every file starts with a warcinfo record
+	info := newRecord(typeInfo, time.Now(), contentFields, fieldsBlock([][2]string{
+		{"software", "github.com/pmdcosta/crawler"},
+		{"format", "WARC File Format 1.1"},
+		{"conformsTo", "http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/"},
+	}))
+	info.add("WARC-Filename", w.name)
+	_, _, err := w.write(info)
+	return err
+}
+
+// write writes a record as a separate gzip member and returns its offset and compressed length
+func (w *Writer) write(r *record) (int64, int64, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(r.bytes()); err != nil {
+		return 0, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, 0, err
+	}
+	offset := w.size
+	n, err := w.file.Write(buf.Bytes())
+	w.size += int64(n)
+	return offset, int64(n), err
+}
+
+// Recorder is a backend that records all the responses of another backend
+type Recorder struct {
+	backend Backend
+	writer  *Writer
+}
+
+// Backend defines the backend whose responses are recorded
+type Backend interface {
+	Do(ctx context.Context, u *url.URL) (*crawler.Response, error)
+}
+
+// NewRecorder instantiates a new backend recording the responses of the backend
+func NewRecorder(backend Backend, writer *Writer) *Recorder {
+	return &Recorder{backend: backend, writer: writer}
+}
+
+// Do executes the request with the backend and records the response
+func (r *Recorder) Do(ctx context.Context, u *url.URL) (*crawler.Response, error) {
+	res, err := r.backend.Do(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.writer.Write(u, res, nil); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,150 @@
+package warc_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmdcosta/crawler/internal/crawler"
+	"github.com/pmdcosta/crawler/internal/warc"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warc")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := zerolog.Nop()
+	w := warc.New(&logger, dir, warc.SetPrefix("test"), warc.SetMaxSize(1))
+
+	// record two pages, each in its own file
+	u, _ := url.Parse("http://www.google.com/b?q=1")
+	res := &crawler.Response{
+		URL:        u,
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}, "Content-Encoding": {"gzip"}},
+		Body:       []byte("body"),
+		Time:       time.Date(2019, 11, 10, 12, 0, 0, 0, time.UTC),
+		Method:     http.MethodGet,
+		RemoteAddr: "127.0.0.1:80",
+	}
+	require.Nil(t, w.PostProcess(context.Background(), &crawler.TaskResult{Task: crawler.Task{URL: u}, Children: map[string]int{"http://google.com/1": 1}, Response: res}))
+	u2, _ := url.Parse("http://google.com/a")
+	require.Nil(t, w.Write(u2, &crawler.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Time: res.Time}, nil))
+
+	// the index is written as the records are, before the writer is closed
+	cdx, err := ioutil.ReadFile(filepath.Join(dir, "test.cdx"))
+	require.Nil(t, err)
+	require.Len(t, strings.Split(strings.TrimRight(string(cdx), "\n"), "\n"), 3)
+	require.Nil(t, w.Close())
+
+	// assert the warc files
+	files, err := filepath.Glob(filepath.Join(dir, "test-*.warc.gz"))
+	require.Nil(t, err)
+	require.Len(t, files, 2)
+	f, err := os.Open(files[0])
+	require.Nil(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.Nil(t, err)
+	content, err := ioutil.ReadAll(gz)
+	require.Nil(t, err)
+	records := strings.Split(string(content), "WARC/1.1\r\n")[1:]
+	require.Len(t, records, 4)
+	require.Contains(t, records[0], "WARC-Type: warcinfo\r\n")
+	require.Contains(t, records[1], "WARC-Type: request\r\n")
+	require.Contains(t, records[1], "GET /b?q=1 HTTP/1.1\r\nHost: www.google.com\r\n")
+	require.Contains(t, records[2], "WARC-Type: response\r\n")
+	require.Contains(t, records[2], "WARC-IP-Address: 127.0.0.1\r\n")
+	require.Contains(t, records[2], "WARC-Payload-Digest: sha1:AIED6RLZ4CFGCJBFYDA2C7XEPLOXQO4U\r\n")
+	require.Contains(t, records[2], "HTTP/1.1 200 OK\r\nContent-Type: text/html; charset=utf-8\r\nContent-Length: 4\r\n\r\nbody")
+	require.NotContains(t, records[2], "Content-Encoding")
+	require.Contains(t, records[3], "WARC-Type: metadata\r\n")
+	require.Contains(t, records[3], "outlink: http://google.com/1\r\n")
+
+	// assert the cdx index
+	cdx, err = ioutil.ReadFile(filepath.Join(dir, "test.cdx"))
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimRight(string(cdx), "\n"), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, " CDX N b a m s k r M S V g", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "com,google)/a 20191110120000 http://google.com/a - 404 "))
+	require.True(t, strings.HasPrefix(lines[2], "com,google)/b?q=1 20191110120000 http://www.google.com/b?q=1 text/html 200 AIED6RLZ4CFGCJBFYDA2C7XEPLOXQO4U - - "))
+
+	// a second crawl with the same prefix keeps the files and the index lines of the first one
+	w = warc.New(&logger, dir, warc.SetPrefix("test"))
+	u3, _ := url.Parse("http://google.com/c")
+	require.Nil(t, w.Write(u3, &crawler.Response{StatusCode: http.StatusOK, Header: http.Header{}, Time: res.Time}, nil))
+	require.Nil(t, w.Close())
+	files, err = filepath.Glob(filepath.Join(dir, "test-*.warc.gz"))
+	require.Nil(t, err)
+	require.Len(t, files, 3)
+	cdx, err = ioutil.ReadFile(filepath.Join(dir, "test.cdx"))
+	require.Nil(t, err)
+	lines = strings.Split(strings.TrimRight(string(cdx), "\n"), "\n")
+	require.Len(t, lines, 4)
+	require.Equal(t, " CDX N b a m s k r M S V g", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "com,google)/a "))
+	require.True(t, strings.HasPrefix(lines[2], "com,google)/b?q=1 "))
+	require.True(t, strings.HasPrefix(lines[3], "com,google)/c "))
+}
+
+func TestWriter_revisit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warc")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	logger := zerolog.Nop()
+	w := warc.New(&logger, dir, warc.SetPrefix("test"))
+
+	// record a revalidated and a fresh cached page
+	u, _ := url.Parse("http://google.com/")
+	fetched := time.Date(2019, 11, 9, 12, 0, 0, 0, time.UTC)
+	res := crawler.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html"}},
+		Body:       []byte("body"),
+		Time:       time.Date(2019, 11, 10, 12, 0, 0, 0, time.UTC),
+		Cache:      crawler.CacheRevalidated,
+		Cached:     fetched,
+	}
+	require.Nil(t, w.Write(u, &res, nil))
+	res.Cache = crawler.CacheHit
+	require.Nil(t, w.Write(u, &res, nil))
+	require.Nil(t, w.Close())
+
+	// assert the revisit records
+	files, err := filepath.Glob(filepath.Join(dir, "test-*.warc.gz"))
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+	f, err := os.Open(files[0])
+	require.Nil(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.Nil(t, err)
+	content, err := ioutil.ReadAll(gz)
+	require.Nil(t, err)
+	records := strings.Split(string(content), "WARC/1.1\r\n")[1:]
+	require.Len(t, records, 7)
+	for _, r := range []string{records[2], records[5]} {
+		require.Contains(t, r, "WARC-Type: revisit\r\n")
+		require.Contains(t, r, "WARC-Refers-To-Target-URI: http://google.com/\r\n")
+		require.Contains(t, r, "WARC-Refers-To-Date: 2019-11-09T12:00:00Z\r\n")
+		require.NotContains(t, r, "body")
+	}
+	require.Contains(t, records[2], "revisit/server-not-modified\r\n")
+	require.Contains(t, records[2], "HTTP/1.1 304 Not Modified\r\n")
+	require.Contains(t, records[5], "revisit/identical-payload-digest\r\n")
+	require.Contains(t, records[5], "HTTP/1.1 200 OK\r\n")
+
+	// revisits are not indexed
+	_, err = os.Stat(filepath.Join(dir, "test.cdx"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"errors"
+	"net/http"
 	"net/url"
 	"time"
 
@@ -30,31 +31,46 @@ type Worker struct {
 	// scraper is the function used to scrape a webpage
 	scraper Scraper
 
+	// metrics of the tasks processed
+	metrics Metrics
+
+	// context the worker context is derived from
+	parent context.Context
+
 	// gracefully shutdown worker
 	ctx    context.Context
 	cancel context.CancelFunc
 	stopCh chan struct{}
+	// how long stopping waits for the task being processed to be received, before dropping it
+	stopTimeout time.Duration
+	dropCh      chan struct{}
 }
 
 // Backend defines the backend client to make http requests
 //go:generate mockgen -destination ../../mocks/backend_mock.go -package mocks -mock_names Backend=MockWorkerBackend github.com/pmdcosta/crawler/internal/worker Backend
 type Backend interface {
-	Do(u *url.URL) ([]byte, error)
+	Do(ctx context.Context, u *url.URL) (*crawler.Response, error)
+}
+
+// Metrics records the tasks processed by the worker
+type Metrics interface {
+	ObserveTask(d time.Duration, err error)
 }
 
 // PreProcessor are custom functions that run before processing a task
 // if an error is return the task will be failed
 // if the ignore bool is true, the task will be ignored
-type PreProcessor func(task *crawler.Task) (ignore bool, err error)
+// the context is cancelled when the worker is stopped
+type PreProcessor func(ctx context.Context, task *crawler.Task) (ignore bool, err error)
 
 // PostProcessor are custom functions that run after processing a task
-type PostProcessor func(task *crawler.TaskResult) error
+type PostProcessor func(ctx context.Context, task *crawler.TaskResult) error
 
 // Option is an optimal configuration option that can be applied to a worker
 type Option func(w *Worker)
 
 // Scraper is the definition of the function used to scrape a webpage
-type Scraper func(root *url.URL, page []byte) map[string]int
+type Scraper func(ctx context.Context, root *url.URL, page []byte) map[string]int
 
 // New instantiates a new worker
 func New(logger *zerolog.Logger, tasks chan crawler.Task, done chan crawler.TaskResult, errors chan crawler.TaskResult, http Backend, scraper Scraper, opts ...Option) *Worker {
@@ -66,6 +82,9 @@ func New(logger *zerolog.Logger, tasks chan crawler.Task, done chan crawler.Task
 		errorQueue: errors,
 		backend:    http,
 		scraper:    scraper,
+		parent:     context.Background(),
+
+		stopTimeout: 5 * time.Second,
 	}
 	for _, opt := range opts {
 		opt(&w)
@@ -87,6 +106,27 @@ func AddPostProcessor(f PostProcessor) Option {
 	}
 }
 
+// SetMetrics sets the metrics recording the tasks processed
+func SetMetrics(m Metrics) Option {
+	return func(w *Worker) {
+		w.metrics = m
+	}
+}
+
+// SetContext sets the parent context of the worker, cancelling it aborts the task being processed and stops the worker
+func SetContext(ctx context.Context) Option {
+	return func(w *Worker) {
+		w.parent = ctx
+	}
+}
+
+// SetStopTimeout sets how long stopping the worker waits for the interrupted task to be received, before dropping it
+func SetStopTimeout(d time.Duration) Option {
+	return func(w *Worker) {
+		w.stopTimeout = d
+	}
+}
+
 // Start starts processing taskQueue
 func (w *Worker) Start() error {
 	if w.ctx != nil {
@@ -94,16 +134,19 @@ func (w *Worker) Start() error {
 	}
 
 	// start worker
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(w.parent)
 	w.ctx = ctx
 	w.cancel = cancel
 	w.stopCh = make(chan struct{})
+	w.dropCh = make(chan struct{})
 	go w.run()
 	return nil
 }
 
-// Stop stops the worker
-func (w Worker) Stop() {
+// Stop stops the worker, aborting the task being processed
+// the interrupted task is sent to the errorQueue, so it must still be received until the worker is stopped
+// if it isn't received within the stop timeout, the task is dropped and Stop returns
+func (w *Worker) Stop() {
 	if w.ctx == nil {
 		return
 	}
@@ -112,47 +155,72 @@ func (w Worker) Stop() {
 	w.logger.Info().Msg("stopping worker")
 
 	// wait for the worker to be gracefully stopped
+	timer := time.NewTimer(w.stopTimeout)
+	defer timer.Stop()
 	select {
 	case <-w.stopCh:
-		return
-	case <-time.After(10 * time.Second):
-		return
+	case <-timer.C:
+		w.logger.Warn().Dur("timeout", w.stopTimeout).Msg("worker not stopped in time, dropping its task")
+		select {
+		case <-w.dropCh:
+		default:
+			close(w.dropCh)
+		}
 	}
 }
 
 // run is the main execution loop of the worker
 func (w *Worker) run() {
 	w.logger.Info().Msg("worker starting...")
+	defer close(w.stopCh)
 	for {
 		select {
 		case <-w.ctx.Done():
 			w.logger.Debug().Msg("worker stopping...")
-			w.stopCh <- struct{}{}
 			return
 		case task, ok := <-w.taskQueue:
 			if ok {
+				start := time.Now()
 				result, err := w.processTask(&task)
+				if w.metrics != nil && !errors.Is(err, context.Canceled) {
+					w.metrics.ObserveTask(time.Since(start), err)
+				}
 				if err != nil {
-					w.errorQueue <- result
+					w.send(w.errorQueue, result)
 				} else {
-					w.doneQueue <- result
+					w.send(w.doneQueue, result)
 				}
 			}
 		}
 	}
 }
 
+// send sends the result of a task, unless the worker is dropping its task because nobody received it in time
+func (w *Worker) send(queue chan crawler.TaskResult, result crawler.TaskResult) {
+	select {
+	case queue <- result:
+	case <-w.dropCh:
+		w.logger.Warn().Str("url", result.Task.URL.String()).Msg("task dropped")
+	}
+}
+
 // run is the main execution loop of the worker
 func (w *Worker) processTask(task *crawler.Task) (crawler.TaskResult, error) {
 	w.logger.Info().Str("url", task.URL.String()).Int("try", task.Tries).Msg("processing task")
 
 	// increment try counter
 	task.Tries += 1
+	if w.ctx.Err() != nil {
+		return w.interrupted(task)
+	}
 
 	// executing pre-processors
 	for _, f := range w.preProcessors {
-		ignore, err := f(task)
+		ignore, err := f(w.ctx, task)
 		if err != nil {
+			if w.ctx.Err() != nil {
+				return w.interrupted(task)
+			}
 			return crawler.TaskResult{Task: *task, Children: nil, Error: &err}, err
 		}
 		if ignore {
@@ -161,23 +229,55 @@ func (w *Worker) processTask(task *crawler.Task) (crawler.TaskResult, error) {
 	}
 
 	// get the webpage
-	body, err := w.backend.Do(task.URL)
+	res, err := w.backend.Do(w.ctx, task.URL)
 	if err != nil {
+		if w.ctx.Err() != nil {
+			return w.interrupted(task)
+		}
 		return crawler.TaskResult{Task: *task, Children: nil, Error: &err}, err
 	}
 
-	// scrape the webpage
-	children := w.scraper(task.URL, body)
-	result := crawler.TaskResult{Task: *task, Children: children}
+	// scrape the webpage, relative links are resolved from the url of the response
+	root := task.URL
+	if res.URL != nil {
+		root = res.URL
+	}
+	// the links of server error pages are not followed
+	var children map[string]int
+	if res.StatusCode < http.StatusInternalServerError {
+		children = w.scraper(w.ctx, root, res.Body)
+	}
+	if w.ctx.Err() != nil {
+		return w.interrupted(task)
+	}
+	result := crawler.TaskResult{Task: *task, Children: children, Response: res}
 
 	// executing post-processors
 	for _, f := range w.postProcessors {
-		if err := f(&result); err != nil {
+		if err = f(w.ctx, &result); err != nil {
 			result.Error = &err
-			return result, err
+			break
 		}
 	}
 
+	// release the body so it is not kept in memory after processing
+	response := *res
+	response.Body = nil
+	result.Response = &response
+	if err != nil {
+		return result, err
+	}
+
 	w.logger.Debug().Str("url", task.URL.String()).Msg("task processed")
 	return result, nil
 }
+
+// interrupted returns a task that was aborted by stopping the worker, without counting the try
+// the error is the context error so the task can be queued again
+func (w *Worker) interrupted(task *crawler.Task) (crawler.TaskResult, error) {
+	w.logger.Debug().Str("url", task.URL.String()).Msg("task interrupted")
+	err := w.ctx.Err()
+	t := *task
+	t.Tries -= 1
+	return crawler.TaskResult{Task: t, Children: nil, Error: &err}, err
+}
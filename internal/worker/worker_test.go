@@ -1,6 +1,7 @@
 package worker_test
 
 import (
+	"context"
 	"errors"
 	"net/url"
 	"testing"
@@ -47,11 +48,11 @@ func TestWorker_ok(t *testing.T) {
 	task := crawler.Task{URL: root, Tries: 1}
 	body := []byte("body")
 	children := map[string]int{"google.com/1": 1}
-	result := crawler.TaskResult{Task: task, Children: children}
+	result := crawler.TaskResult{Task: task, Children: children, Response: &crawler.Response{}}
 
 	// mock scraper
 	var scraperCall bool
-	scraper := func(arg *url.URL, page []byte) map[string]int {
+	scraper := func(ctx context.Context, arg *url.URL, page []byte) map[string]int {
 		scraperCall = true
 		require.Equal(t, root, arg)
 		require.Equal(t, body, page)
@@ -64,7 +65,7 @@ func TestWorker_ok(t *testing.T) {
 	defer w.Stop()
 
 	// mock backend
-	w.backend.EXPECT().Do(root).Times(1).Return(body, nil)
+	w.backend.EXPECT().Do(gomock.Any(), root).Times(1).Return(&crawler.Response{Body: body}, nil)
 
 	// send the task to the worker
 	w.tasks <- crawler.Task{URL: root}
@@ -84,11 +85,11 @@ func TestWorker_process(t *testing.T) {
 	task := crawler.Task{URL: root, Tries: 1}
 	body := []byte("body")
 	children := map[string]int{"google.com/1": 1}
-	result := crawler.TaskResult{Task: task, Children: children}
+	result := crawler.TaskResult{Task: task, Children: children, Response: &crawler.Response{}}
 
 	// mock scraper
 	var scraperCall bool
-	scraper := func(arg *url.URL, page []byte) map[string]int {
+	scraper := func(ctx context.Context, arg *url.URL, page []byte) map[string]int {
 		scraperCall = true
 		require.Equal(t, root, arg)
 		require.Equal(t, body, page)
@@ -97,14 +98,14 @@ func TestWorker_process(t *testing.T) {
 
 	// add pre and post processors
 	var preCall, postCall bool
-	preProcess := func(arg *crawler.Task) (ignore bool, err error) {
+	preProcess := func(ctx context.Context, arg *crawler.Task) (ignore bool, err error) {
 		preCall = true
 		require.Equal(t, &task, arg)
 		return false, nil
 	}
-	postProcess := func(arg *crawler.TaskResult) error {
+	postProcess := func(ctx context.Context, arg *crawler.TaskResult) error {
 		postCall = true
-		require.Equal(t, &result, arg)
+		require.Equal(t, &crawler.TaskResult{Task: task, Children: children, Response: &crawler.Response{Body: body}}, arg)
 		return nil
 	}
 
@@ -114,7 +115,7 @@ func TestWorker_process(t *testing.T) {
 	defer w.Stop()
 
 	// mock backend
-	w.backend.EXPECT().Do(root).Times(1).Return(body, nil)
+	w.backend.EXPECT().Do(gomock.Any(), root).Times(1).Return(&crawler.Response{Body: body}, nil)
 
 	// send the task to the worker
 	w.tasks <- crawler.Task{URL: root}
@@ -138,14 +139,14 @@ func TestWorker_ignore(t *testing.T) {
 
 	// mock scraper
 	var scraperCall bool
-	scraper := func(arg *url.URL, page []byte) map[string]int {
+	scraper := func(ctx context.Context, arg *url.URL, page []byte) map[string]int {
 		scraperCall = true
 		return nil
 	}
 
 	// add pre and post processors
 	var preCall bool
-	preProcess := func(arg *crawler.Task) (ignore bool, err error) {
+	preProcess := func(ctx context.Context, arg *crawler.Task) (ignore bool, err error) {
 		preCall = true
 		require.Equal(t, &task, arg)
 		return true, nil
@@ -178,7 +179,7 @@ func TestWorker_error(t *testing.T) {
 
 	// mock scraper
 	var scraperCall bool
-	scraper := func(arg *url.URL, page []byte) map[string]int {
+	scraper := func(ctx context.Context, arg *url.URL, page []byte) map[string]int {
 		scraperCall = true
 		return nil
 	}
@@ -189,7 +190,7 @@ func TestWorker_error(t *testing.T) {
 	defer w.Stop()
 
 	// mock backend
-	w.backend.EXPECT().Do(root).Times(1).Return(nil, err)
+	w.backend.EXPECT().Do(gomock.Any(), root).Times(1).Return(nil, err)
 
 	// send the task to the worker
 	w.tasks <- crawler.Task{URL: root}
@@ -203,3 +204,86 @@ func TestWorker_error(t *testing.T) {
 	}
 	require.False(t, scraperCall)
 }
+
+func TestWorker_cancel(t *testing.T) {
+	root, _ := url.Parse("http://google.com")
+	task := crawler.Task{URL: root}
+	err := context.Canceled
+	result := crawler.TaskResult{Task: task, Children: nil, Error: &err}
+
+	// mock scraper
+	var scraperCall bool
+	scraper := func(ctx context.Context, arg *url.URL, page []byte) map[string]int {
+		scraperCall = true
+		return nil
+	}
+
+	// start worker
+	ctx, cancel := context.WithCancel(context.Background())
+	w := newTestWorker(t, scraper, worker.SetContext(ctx))
+	require.Nil(t, w.Start())
+
+	// mock backend blocking until the request is cancelled
+	started := make(chan struct{})
+	w.backend.EXPECT().Do(gomock.Any(), root).Times(1).DoAndReturn(func(ctx context.Context, u *url.URL) (*crawler.Response, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	// send the task to the worker and cancel it while it is being processed
+	w.tasks <- crawler.Task{URL: root}
+	<-started
+	cancel()
+
+	// assert the task is returned without counting the try
+	select {
+	case r := <-w.errors:
+		require.Equal(t, result, r)
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "result not received")
+	}
+	require.False(t, scraperCall)
+
+	// the worker stops right away
+	stopped := make(chan struct{})
+	go func() {
+		w.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "worker not stopped")
+	}
+}
+
+func TestWorker_stop(t *testing.T) {
+	root, _ := url.Parse("http://google.com")
+
+	// start worker
+	w := newTestWorker(t, nil, worker.SetStopTimeout(50*time.Millisecond))
+	require.Nil(t, w.Start())
+
+	// mock backend blocking until the request is cancelled
+	started := make(chan struct{})
+	w.backend.EXPECT().Do(gomock.Any(), root).Times(1).DoAndReturn(func(ctx context.Context, u *url.URL) (*crawler.Response, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	w.tasks <- crawler.Task{URL: root}
+	<-started
+
+	// the interrupted task is never received, the worker drops it and stops
+	stopped := make(chan struct{})
+	go func() {
+		w.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(1 * time.Second):
+		require.FailNow(t, "worker not stopped")
+	}
+}
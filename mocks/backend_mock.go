@@ -5,7 +5,9 @@
 package mocks
 
 import (
+	context "context"
 	gomock "github.com/golang/mock/gomock"
+	crawler "github.com/pmdcosta/crawler/internal/crawler"
 	url "net/url"
 	reflect "reflect"
 )
@@ -34,14 +36,16 @@ func (m *MockWorkerBackend) EXPECT() *MockWorkerBackendMockRecorder {
 }
 
 // Do mocks base method
-func (m *MockWorkerBackend) Do(arg0 *url.URL) ([]byte, error) {
-	ret := m.ctrl.Call(m, "Do", arg0)
-	ret0, _ := ret[0].([]byte)
+func (m *MockWorkerBackend) Do(arg0 context.Context, arg1 *url.URL) (*crawler.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Do", arg0, arg1)
+	ret0, _ := ret[0].(*crawler.Response)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Do indicates an expected call of Do
-func (mr *MockWorkerBackendMockRecorder) Do(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockWorkerBackend)(nil).Do), arg0)
+func (mr *MockWorkerBackendMockRecorder) Do(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockWorkerBackend)(nil).Do), arg0, arg1)
 }